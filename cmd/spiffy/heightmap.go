@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kpango/glg"
+
+	pkg "github.com/gucio321/spiffy/pkg"
+	"github.com/gucio321/spiffy/pkg/heightmap"
+)
+
+// heightmapCommand converts a grayscale PNG heightmap to layered contours (spiffy heightmap [flags] image.png).
+func heightmapCommand(args []string) {
+	var job jobFlags
+
+	flags := flag.NewFlagSet("heightmap", flag.ExitOnError)
+	job.register(flags)
+	depth := flags.Float64("depth", 0, "depth (in mm) of the deepest (black) points")
+	stepDown := flags.Float64("step", 1, "max step down (in mm) between layers")
+	invert := flags.Bool("invert", false, "white points are the deepest")
+	optimize := flags.Bool("optimize", false, "reorder contours of every layer to minimize travel moves")
+	simplify := flags.Float64("simplify", 0, "tolerance (in mm) of contours simplification (0 merges only collinear lines)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: spiffy heightmap [flags] image.png")
+		fmt.Fprintln(flags.Output(), "The image is scaled to fit the workspace. Darker points are deeper.")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		glg.Fatal("heightmap file is required")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		glg.Fatalf("Cannot open file %s: %v", flags.Arg(0), err)
+	}
+
+	h, err := heightmap.Decode(file, *invert)
+	file.Close()
+	if err != nil {
+		glg.Fatalf("Cannot read file %s: %v", flags.Arg(0), err)
+	}
+
+	result := pkg.NewSpiffy()
+	job.configure(result, flags.Arg(0))
+	result.Simplify(*simplify)
+	if *optimize {
+		result.Optimize()
+	}
+
+	job.write(result.Heightmap(h, *depth, *stepDown))
+}
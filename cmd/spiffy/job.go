@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kpango/glg"
+
+	pkg "github.com/gucio321/spiffy/pkg"
+	"github.com/gucio321/spiffy/pkg/gcb"
+)
+
+// jobFlags are flags shared by subcommands generating GCode (see revolveCommand).
+type jobFlags struct {
+	output, workspaceName, dialect string
+	scale, dpi, tolerance          float64
+	precision                      int
+	absolute, nativeArcs, collect  bool
+	noLineComments                 bool
+	startZ, depthDelta             float64
+	feed, travelFeed, plungeFeed   float64
+}
+
+// register adds flags to the flag set.
+func (f *jobFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.output, "o", "", "output file path (standard output if empty)")
+	flags.StringVar(&f.workspaceName, "workspace", "", "workspace name from workspaces.json")
+	flags.StringVar(&f.dialect, "dialect", gcb.DefaultDialect.Name(), "firmware dialect (marlin, grbl, linuxcnc, klipper)")
+	flags.IntVar(&f.precision, "precision", gcb.DialectPrecision, "decimal places of numbers (-1 for the shortest exact, -2 for dialect's default)")
+	flags.Float64Var(&f.tolerance, "tolerance", gcb.DefaultTolerance, "max distance (in mm) between curves and lines approximating them")
+	flags.Float64Var(&f.startZ, "sz", 0, "start Z (use along with -dz for delta zet)")
+	flags.Float64Var(&f.depthDelta, "dz", float64(gcb.BaseDepth), "delta Z (use along with -sz for start zet)")
+	flags.Float64Var(&f.feed, "feed", 0, "feed rate (mm/min) of drawing (G1) moves; 0 to not set")
+	flags.Float64Var(&f.travelFeed, "travel-feed", 0, "feed rate (mm/min) of travel (G0) moves; 0 to not set")
+	flags.Float64Var(&f.plungeFeed, "plunge-feed", 0, "feed rate (mm/min) of going down; 0 to not set")
+	flags.BoolVar(&f.absolute, "absolute", false, "emit absolute (G90) coordinates instead of relative (G91) ones")
+	flags.BoolVar(&f.nativeArcs, "arcs", false, "use native G2/G3 arc moves")
+	flags.BoolVar(&f.collect, "collect-violations", false, "do not stop on the first point outside the workspace; report all of them")
+	flags.BoolVar(&f.noLineComments, "nlc", false, "no line comments")
+}
+
+// registerSVG adds flags of subcommands reading SVG files (see load).
+func (f *jobFlags) registerSVG(flags *flag.FlagSet) {
+	f.register(flags)
+	flags.Float64Var(&f.scale, "s", 1.0, "Scale factor")
+	flags.Float64Var(&f.dpi, "dpi", pkg.DefaultDPI, "px per inch used to convert px to mm")
+}
+
+// load parses the SVG file and configures it with the flags.
+func (f *jobFlags) load(inputFilePath string) *pkg.Spiffy {
+	data, err := os.ReadFile(inputFilePath)
+	if err != nil {
+		glg.Fatalf("Cannot read file %s: %v", inputFilePath, err)
+	}
+
+	result, err := pkg.Parse(data)
+	if err != nil {
+		glg.Fatalf("Cannot parse file %s: %v", inputFilePath, err)
+	}
+
+	result.DPI(f.dpi)
+	result.Scale(float32(f.scale))
+	f.configure(result, inputFilePath)
+
+	return result
+}
+
+// configure sets the flags up (inputFilePath is used as a job name).
+func (f *jobFlags) configure(result *pkg.Spiffy, inputFilePath string) {
+	if f.tolerance <= 0 {
+		glg.Fatal("-tolerance must be positive")
+	}
+
+	if f.workspaceName != "" {
+		result.WorkspaceName(f.workspaceName)
+	}
+
+	dialect, err := gcb.DialectByName(f.dialect)
+	if err != nil {
+		glg.Fatalf("Invalid -dialect: %v", err)
+	}
+
+	if f.startZ != 0 {
+		result.Depths(f.depthDelta, f.startZ)
+	}
+
+	if f.nativeArcs {
+		result.NativeArcs()
+	}
+
+	if f.collect {
+		result.CollectViolations()
+	}
+
+	if f.absolute {
+		result.AbsolutePositioning()
+	}
+
+	result.Tolerance(f.tolerance)
+	result.Dialect(dialect)
+	result.Precision(f.precision)
+	result.Feeds(gcb.Feeds{
+		Draw:   float32(f.feed),
+		Travel: float32(f.travelFeed),
+		Plunge: float32(f.plungeFeed),
+	})
+	result.JobName(strings.TrimSuffix(filepath.Base(inputFilePath), filepath.Ext(inputFilePath)))
+}
+
+// write writes the generated GCode (or reports the error).
+func (f *jobFlags) write(gcode *gcb.GCodeBuilder, err error) {
+	if err != nil {
+		if gcode != nil && !errors.Is(err, gcb.ErrOutOfBounds) {
+			gcode.Dump()
+		}
+
+		glg.Fatalf("Cannot generate GCode: %v", err)
+	}
+
+	gcode.Comments(!f.noLineComments, false)
+
+	if f.output == "" {
+		if _, err := gcode.WriteTo(os.Stdout); err != nil {
+			glg.Fatalf("Cannot write GCode: %v", err)
+		}
+
+		return
+	}
+
+	if err := writeGCode(f.output, gcode); err != nil {
+		glg.Fatalf("Cannot write file %s: %v", f.output, err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/kpango/glg"
+)
+
+// loftCommand morphs a top outline into a bottom one (spiffy loft [flags] file.svg).
+func loftCommand(args []string) {
+	var job jobFlags
+
+	flags := flag.NewFlagSet("loft", flag.ExitOnError)
+	job.registerSVG(flags)
+	top := flags.String("top", "", "id or label of the top outline (path or layer)")
+	bottom := flags.String("bottom", "", "id or label of the bottom outline (path or layer)")
+	depth := flags.Float64("depth", 0, "total depth (in mm) of the part")
+	stepDown := flags.Float64("step", 1, "max step down (in mm) between contours")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: spiffy loft [flags] file.svg")
+		fmt.Fprintln(flags.Output(), "Without -top and -bottom the first two closed paths of the file are used.")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		glg.Fatal("SVG file is required")
+	}
+
+	if (*top == "") != (*bottom == "") {
+		glg.Fatal("-top and -bottom must be used together")
+	}
+
+	result := job.load(flags.Arg(0))
+	job.write(result.Loft(*top, *bottom, *depth, *stepDown))
+}
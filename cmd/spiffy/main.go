@@ -2,9 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	inkscape "github.com/galihrivanto/go-inkscape"
 	"github.com/hajimehoshi/ebiten/v2"
@@ -23,6 +27,10 @@ type Flags struct {
 	OutputFilePath string
 	// Scale up/down SVG
 	Scale float64
+	// DPI is number of px (SVG user units) per inch.
+	DPI float64
+	// Size is a target physical size of the document in mm (WxH, one of them may be omitted).
+	Size string
 	// CommentsAbove puts additional debug comments in gcode if true.
 	CommentsAbove bool
 	// NoLineComments removes comments with position hints if true
@@ -33,6 +41,12 @@ type Flags struct {
 	RepeatN int
 	// RepeatDepth - go down this much after each repeat.
 	RepeatDepth float64
+	// Helical goes down gradually while drawing repeats instead of stepping down before each of them.
+	Helical bool
+	// WallAngle is a wall angle (degrees from horizontal) of the formed part. Repeats are offset inward if set.
+	WallAngle float64
+	// WallProfile is a wall angle varying with depth (depth:angle,... in mm and degrees).
+	WallProfile string
 	// startZ is called calibrationZ in another part of the code.
 	// It describes how much to go down before starting code execution.
 	StartZ float64
@@ -41,7 +55,35 @@ type Flags struct {
 	// WorkspaceName is a workspace name from workspaces.json
 	WorkspaceName string
 	// Workspace is a custom workspace
-	Workspace  *workspace.Workspace
+	Workspace *workspace.Workspace
+	// Tolerance is max distance (in mm) between curves and lines approximating them.
+	Tolerance float64
+	// Simplify is a tolerance (in mm) of polyline simplification.
+	Simplify float64
+	// Feed is a feed rate (mm/min) of drawing moves (0 to not set).
+	Feed float64
+	// TravelFeed is a feed rate (mm/min) of travel moves (0 to not set).
+	TravelFeed float64
+	// PlungeFeed is a feed rate (mm/min) of going down (0 to not set).
+	PlungeFeed float64
+	// Dialect is a firmware dialect (marlin, grbl, linuxcnc or klipper).
+	Dialect string
+	// CommentColumn is a column of line comments (0 to align with the longest line, -1 to not align).
+	CommentColumn int
+	// Precision is a number of decimal places (-1 for the shortest exact, -2 for dialect's default).
+	Precision int
+	// Preamble and Postamble are paths to text/template files with code executed before/after the job.
+	Preamble, Postamble string
+	// Absolute emits absolute (G90) coordinates.
+	Absolute bool
+	// CollectViolations reports all points outside the workspace at once.
+	CollectViolations bool
+	// Optimize reorders paths to minimize travel moves.
+	Optimize bool
+	// NativeArcs enables G2/G3 arc moves (if not enabled in the workspace).
+	NativeArcs bool
+	// NoInkscape skips Inkscape pre-processing (SVG shapes are converted by spiffy itself).
+	NoInkscape bool
 	force      bool
 	preset     string
 	makePreset bool
@@ -49,6 +91,23 @@ type Flags struct {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "stats":
+			statsCommand(os.Args[2:])
+			return
+		case "revolve":
+			revolveCommand(os.Args[2:])
+			return
+		case "loft":
+			loftCommand(os.Args[2:])
+			return
+		case "heightmap":
+			heightmapCommand(os.Args[2:])
+			return
+		}
+	}
+
 	var f Flags
 	f.Workspace = &workspace.Workspace{
 		Name:        "custom",
@@ -57,22 +116,44 @@ func main() {
 	flag.StringVar(&f.InputFilePath, "i", "", "input file path")
 	flag.StringVar(&f.OutputFilePath, "o", "", "output file path")
 	flag.Float64Var(&f.Scale, "s", 1.0, "Scale factor")
+	flag.Float64Var(&f.DPI, "dpi", pkg.DefaultDPI, "px per inch used to convert px to mm")
+	flag.StringVar(&f.Size, "size", "", "scale document to the physical size WxH in mm (e.g. 100x50, 100x or x50)")
 	flag.BoolVar(&f.NoLineComments, "nlc", false, "no line comments")
 	flag.BoolVar(&f.CommentsAbove, "ca", false, "comments above")
+	flag.IntVar(&f.CommentColumn, "comment-column", gcb.AlignToLongest, "column of line comments (0 aligns with the longest line, -1 does not align)")
 	flag.BoolVar(&f.View, "v", false, "view")
 	flag.IntVar(&f.RepeatN, "rn", 0, "repeat N times (use with -rd)")
 	flag.Float64Var(&f.RepeatDepth, "rd", 5, "repeat depth (use with -rn)")
+	flag.BoolVar(&f.Helical, "helical", false, "go down gradually along the paths while repeating instead of stepping down (use with -rn)")
+	flag.Float64Var(&f.WallAngle, "wall-angle", 0, "wall angle (degrees from horizontal); offset closed paths inward while repeating (use with -rn)")
+	flag.StringVar(&f.WallProfile, "wall-profile", "", "wall angle varying with depth as depth:angle,... (e.g. 0:60,20:45); overrides -wall-angle")
 	flag.Float64Var(&f.StartZ, "sz", 0, "start Z (use along with -dz for delta zet)")
 	flag.Float64Var(&f.DepthDelta, "dz", float64(gcb.BaseDepth), "delta Z (use along with -sz for start zet)")
 	flag.BoolVar(&f.force, "f", false, "force")
 	flag.StringVar(&f.preset, "preset", "", "JSON preset file path. This will override all other flags")
 	flag.BoolVar(&f.makePreset, "make-preset", false, "auto-generate preset")
 	flag.BoolVar(&f.showGCode, "show-gcode", false, "print resulting GCode even if -o is set")
+	flag.Float64Var(&f.Tolerance, "tolerance", gcb.DefaultTolerance, "max distance (in mm) between curves and lines approximating them")
+	flag.Float64Var(&f.Simplify, "simplify", 0, "tolerance (in mm) of polyline simplification (0 merges only zero-length and collinear lines)")
+	flag.Float64Var(&f.Feed, "feed", 0, "feed rate (mm/min) of drawing (G1) moves; 0 to not set")
+	flag.Float64Var(&f.TravelFeed, "travel-feed", 0, "feed rate (mm/min) of travel (G0) moves; 0 to not set")
+	flag.Float64Var(&f.PlungeFeed, "plunge-feed", 0, "feed rate (mm/min) of going down; 0 to not set")
+	flag.StringVar(&f.Dialect, "dialect", gcb.DefaultDialect.Name(), "firmware dialect (marlin, grbl, linuxcnc, klipper)")
+	flag.IntVar(&f.Precision, "precision", gcb.DialectPrecision, "decimal places of numbers (-1 for the shortest exact, -2 for dialect's default)")
+	flag.StringVar(&f.Preamble, "preamble", "", "preamble template file (overrides workspace's and dialect's default)")
+	flag.StringVar(&f.Postamble, "postamble", "", "postamble template file (overrides workspace's and dialect's default)")
+	flag.BoolVar(&f.Absolute, "absolute", false, "emit absolute (G90) coordinates instead of relative (G91) ones")
+	flag.BoolVar(&f.CollectViolations, "collect-violations", false, "do not stop on the first point outside the workspace; report all of them")
+	flag.BoolVar(&f.Optimize, "optimize", false, "reorder paths to minimize travel moves")
+	flag.BoolVar(&f.NativeArcs, "arcs", false, "use native G2/G3 arc moves")
+	flag.BoolVar(&f.NoInkscape, "no-inkscape", false, "do not pre-process SVG with Inkscape")
 	flag.StringVar(&f.WorkspaceName, "workspace", "", "workspace name from workspaces.json")
 	flag.IntVar(&f.Workspace.MinX, "minx", 0, "workspace min x")
 	flag.IntVar(&f.Workspace.MinY, "miny", 0, "workspace min y")
 	flag.IntVar(&f.Workspace.MaxX, "maxx", 0, "workspace max x")
 	flag.IntVar(&f.Workspace.MaxY, "maxy", 0, "workspace max y")
+	flag.IntVar(&f.Workspace.BaseX, "basex", workspace.DefaultBaseX, "workspace base (start) x")
+	flag.IntVar(&f.Workspace.BaseY, "basey", workspace.DefaultBaseY, "workspace base (start) y")
 	flag.Parse()
 
 	if f.makePreset {
@@ -100,35 +181,23 @@ func main() {
 		glg.Fatal("Please specify -dz (-f to force)")
 	}
 
+	if f.Tolerance <= 0 {
+		glg.Fatal("-tolerance must be positive")
+	}
+
 	if _, err := os.Stat(f.InputFilePath); os.IsNotExist(err) {
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	inkscapeProxy := inkscape.NewProxy(inkscape.Verbose(true))
-	if err := inkscapeProxy.Run(); err != nil {
-		glg.Fatalf("Cannot run inkscape: %v", err)
+	inputFile := f.InputFilePath
+	if !f.NoInkscape {
+		inputFile = inkscapePreprocess(f.InputFilePath)
 	}
 
-	defer inkscapeProxy.Close()
-
-	glg.Infof("running inkscape pre-processing")
-	convertedFile := f.InputFilePath + ".spiffy.svg"
-	inkscapeProxy.RawCommands(
-		fmt.Sprintf("file-open:%s", f.InputFilePath),
-		fmt.Sprintf("export-filename:%s", convertedFile),
-		"export-type:svg",
-		"select-all",
-		"object-to-path",
-		"path-simplify",
-		"export-do",
-	)
-
-	glg.Info("inkscape done.")
-
-	data, err := os.ReadFile(convertedFile)
+	data, err := os.ReadFile(inputFile)
 	if err != nil {
-		glg.Fatalf("Cannot read file %s: %v", f.InputFilePath, err)
+		glg.Fatalf("Cannot read file %s: %v", inputFile, err)
 	}
 
 	result, err := pkg.Parse(data)
@@ -146,27 +215,91 @@ func main() {
 
 	if f.RepeatN > 0 {
 		result.Repeat(f.RepeatN, f.RepeatDepth)
+		switch {
+		case f.Helical && (f.WallAngle != 0 || f.WallProfile != ""):
+			glg.Fatal("-helical cannot be used with -wall-angle nor -wall-profile")
+		case f.Helical:
+			result.Strategy(pkg.RepeatHelical)
+		case f.WallProfile != "":
+			profile, err := parseWallProfile(f.WallProfile)
+			if err != nil {
+				glg.Fatalf("Invalid -wall-profile %s: %v", f.WallProfile, err)
+			}
+
+			result.Strategy(pkg.RepeatOffset).WallProfile(profile)
+		case f.WallAngle != 0:
+			result.Strategy(pkg.RepeatOffset).WallAngle(f.WallAngle)
+		}
 	}
 
 	if f.StartZ != 0 {
 		result.Depths(f.DepthDelta, f.StartZ)
 	}
 
+	if f.NativeArcs {
+		result.NativeArcs()
+	}
+
+	if f.Optimize {
+		result.Optimize()
+	}
+
+	if f.CollectViolations {
+		result.CollectViolations()
+	}
+
+	if f.Absolute {
+		result.AbsolutePositioning()
+	}
+
+	if f.Size != "" {
+		w, h, err := parseSize(f.Size)
+		if err != nil {
+			glg.Fatalf("Invalid -size %s: %v", f.Size, err)
+		}
+
+		result.Size(w, h)
+	}
+
+	result.DPI(f.DPI)
+	result.Tolerance(f.Tolerance)
+	result.Simplify(f.Simplify)
+
+	dialect, err := gcb.DialectByName(f.Dialect)
+	if err != nil {
+		glg.Fatalf("Invalid -dialect: %v", err)
+	}
+
+	result.Dialect(dialect)
+	result.Precision(f.Precision)
+	result.JobName(strings.TrimSuffix(filepath.Base(f.InputFilePath), filepath.Ext(f.InputFilePath)))
+	result.Preamble(readTemplate(f.Preamble), readTemplate(f.Postamble))
+	result.Feeds(gcb.Feeds{
+		Draw:   float32(f.Feed),
+		Travel: float32(f.TravelFeed),
+		Plunge: float32(f.PlungeFeed),
+	})
 	result.Scale(float32(f.Scale))
 	gcode, err := result.GCode()
 	if err != nil {
-		gcode.Dump()
+		if !errors.Is(err, gcb.ErrOutOfBounds) {
+			gcode.Dump()
+		}
+
 		glg.Fatalf("Cannot generate GCode: %v", err)
 	}
 
 	gcode.Comments(!f.NoLineComments, f.CommentsAbove)
+	gcode.AlignComments(f.CommentColumn)
 
 	if (f.OutputFilePath == "" && !f.View) || f.showGCode {
-		fmt.Println(gcode)
+		if _, err := gcode.WriteTo(os.Stdout); err != nil {
+			glg.Fatalf("Cannot write GCode: %v", err)
+		}
 	}
 
 	if f.OutputFilePath != "" {
-		if err := os.WriteFile(f.OutputFilePath, []byte(gcode.String()), 0644); err != nil {
+		if err := writeGCode(f.OutputFilePath, gcode); err != nil {
 			glg.Fatalf("Cannot write file %s: %v", f.OutputFilePath, err)
 		}
 	}
@@ -178,3 +311,111 @@ func main() {
 		}
 	}
 }
+
+// inkscapePreprocess converts all objects in SVG to paths with Inkscape and returns path to the converted file.
+func inkscapePreprocess(inputFilePath string) string {
+	inkscapeProxy := inkscape.NewProxy(inkscape.Verbose(true))
+	if err := inkscapeProxy.Run(); err != nil {
+		glg.Fatalf("Cannot run inkscape: %v (use -no-inkscape to skip pre-processing)", err)
+	}
+
+	defer inkscapeProxy.Close()
+
+	glg.Infof("running inkscape pre-processing")
+	convertedFile := inputFilePath + ".spiffy.svg"
+	inkscapeProxy.RawCommands(
+		fmt.Sprintf("file-open:%s", inputFilePath),
+		fmt.Sprintf("export-filename:%s", convertedFile),
+		"export-type:svg",
+		"select-all",
+		"object-to-path",
+		"path-simplify",
+		"export-do",
+	)
+
+	glg.Info("inkscape done.")
+
+	return convertedFile
+}
+
+// writeGCode streams GCode to the file.
+func writeGCode(path string, gcode *gcb.GCodeBuilder) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := gcode.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// readTemplate reads template file (if path is not empty).
+func readTemplate(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		glg.Fatalf("Cannot read template %s: %v", path, err)
+	}
+
+	return string(data)
+}
+
+// parseSize parses size in form of WxH. One of dimensions may be omitted (e.g. 100x).
+func parseSize(s string) (w, h float64, err error) {
+	parts := strings.Split(strings.ToLower(s), "x")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected WxH")
+	}
+
+	values := make([]float64, 2)
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+
+		if values[i], err = strconv.ParseFloat(part, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if values[0] <= 0 && values[1] <= 0 {
+		return 0, 0, fmt.Errorf("at least one dimension must be positive")
+	}
+
+	return values[0], values[1], nil
+}
+
+// parseWallProfile parses wall profile in form of depth:angle,depth:angle,...
+func parseWallProfile(s string) (pkg.WallProfile, error) {
+	var points []pkg.WallPoint
+	for _, part := range strings.Split(s, ",") {
+		depth, angle, found := strings.Cut(part, ":")
+		if !found {
+			return nil, fmt.Errorf("expected depth:angle, got %s", part)
+		}
+
+		var (
+			point pkg.WallPoint
+			err   error
+		)
+
+		if point.Depth, err = strconv.ParseFloat(strings.TrimSpace(depth), 64); err != nil {
+			return nil, err
+		}
+
+		if point.Angle, err = strconv.ParseFloat(strings.TrimSpace(angle), 64); err != nil {
+			return nil, err
+		}
+
+		points = append(points, point)
+	}
+
+	return pkg.LinearWall(points...), nil
+}
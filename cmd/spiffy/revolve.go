@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kpango/glg"
+)
+
+// revolveCommand revolves a half-profile into circular contours (spiffy revolve [flags] profile.svg).
+func revolveCommand(args []string) {
+	var job jobFlags
+
+	flags := flag.NewFlagSet("revolve", flag.ExitOnError)
+	job.registerSVG(flags)
+	stepDown := flags.Float64("step", 1, "step down (in mm) between contours")
+	spiral := flags.Bool("spiral", false, "go down along spirals between contours instead of lifting the head")
+	center := flags.String("center", "", "axis of the part as X,Y in printer's coordinates (middle of the workspace if empty)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: spiffy revolve [flags] profile.svg")
+		fmt.Fprintln(flags.Output(), "The profile's X is a radius (from the left edge of the document), Y is a depth.")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		glg.Fatal("profile file is required")
+	}
+
+	result := job.load(flags.Arg(0))
+
+	if *center != "" {
+		x, y, err := parsePoint(*center)
+		if err != nil {
+			glg.Fatalf("Invalid -center %s: %v", *center, err)
+		}
+
+		result.Center(x, y)
+	}
+
+	job.write(result.Revolve(*stepDown, *spiral))
+}
+
+// parsePoint parses a point in form of X,Y.
+func parsePoint(s string) (x, y float64, err error) {
+	xs, ys, found := strings.Cut(s, ",")
+	if !found {
+		return 0, 0, fmt.Errorf("expected X,Y")
+	}
+
+	if x, err = strconv.ParseFloat(strings.TrimSpace(xs), 64); err != nil {
+		return 0, 0, err
+	}
+
+	if y, err = strconv.ParseFloat(strings.TrimSpace(ys), 64); err != nil {
+		return 0, 0, err
+	}
+
+	return x, y, nil
+}
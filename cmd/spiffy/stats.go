@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kpango/glg"
+
+	"github.com/gucio321/spiffy/pkg/gcb"
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+// StatsOutput is a JSON form of gcb.Stats.
+type StatsOutput struct {
+	DrawLength, TravelLength float64
+	Lifts                    int
+	MinZ, MaxZ               float64
+	// BBox is nil if nothing is drawn.
+	BBox *geom.Rect
+	// Time is an estimated time in seconds.
+	Time   float64
+	Passes []StatsOutput `json:",omitempty"`
+}
+
+func newStatsOutput(stats gcb.Stats) StatsOutput {
+	result := StatsOutput{
+		DrawLength:   stats.DrawLength,
+		TravelLength: stats.TravelLength,
+		Lifts:        stats.Lifts,
+		MinZ:         stats.MinZ,
+		MaxZ:         stats.MaxZ,
+		Time:         stats.Time.Seconds(),
+	}
+
+	if !stats.BBox.IsEmpty() {
+		result.BBox = &stats.BBox
+	}
+
+	for _, pass := range stats.Passes {
+		result.Passes = append(result.Passes, newStatsOutput(pass))
+	}
+
+	return result
+}
+
+// statsCommand prints statistics of a G-code file (spiffy stats [-json] file.gcode).
+func statsCommand(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print statistics as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: spiffy stats [-json] file.gcode")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		glg.Fatal("G-code file is required")
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		glg.Fatal(err)
+	}
+
+	builder, err := gcb.NewGCodeBuilderFromGCode(data)
+	if err != nil {
+		glg.Fatalf("Cannot read %s: %v", flags.Arg(0), err)
+	}
+
+	stats := builder.Stats()
+
+	if *asJSON {
+		out, err := json.MarshalIndent(newStatsOutput(stats), "", "\t")
+		if err != nil {
+			glg.Fatalf("Cannot encode statistics: %v", err)
+		}
+
+		fmt.Println(string(out))
+
+		return
+	}
+
+	fmt.Print(formatStats(stats, ""))
+	for i, pass := range stats.Passes {
+		fmt.Printf("Pass %d:\n%s", i+1, formatStats(pass, "\t"))
+	}
+}
+
+// formatStats returns human-readable statistics (every line starts with indent).
+func formatStats(stats gcb.Stats, indent string) string {
+	result := &strings.Builder{}
+	line := func(name, format string, args ...any) {
+		fmt.Fprintf(result, "%s%-16s"+format+"\n", append([]any{indent, name + ":"}, args...)...)
+	}
+
+	line("Drawing length", "%.2f mm", stats.DrawLength)
+	line("Travel length", "%.2f mm", stats.TravelLength)
+	line("Lifts", "%d", stats.Lifts)
+	line("Z range", "%.2f .. %.2f mm", stats.MinZ, stats.MaxZ)
+	if !stats.BBox.IsEmpty() {
+		line("Bounding box", "X %.2f .. %.2f, Y %.2f .. %.2f mm",
+			stats.BBox.Min.X, stats.BBox.Max.X, stats.BBox.Min.Y, stats.BBox.Max.Y)
+	}
+
+	line("Estimated time", "%v", stats.Time.Round(time.Second))
+
+	return result.String()
+}
@@ -7,7 +7,6 @@ require (
 	github.com/galihrivanto/go-inkscape v0.1.5
 	github.com/hajimehoshi/ebiten/v2 v2.8.0
 	github.com/kpango/glg v1.6.15
-	github.com/rustyoz/svg v0.0.0-20240916132020-37383a9eebb1
 	golang.org/x/image v0.21.0
 )
 
@@ -19,8 +18,6 @@ require (
 	github.com/goccy/go-json v0.10.2 // indirect
 	github.com/jezek/xgb v1.1.1 // indirect
 	github.com/kpango/fastime v1.1.9 // indirect
-	github.com/rustyoz/Mtransform v0.0.0-20190224104252-60c8c35a3681 // indirect
-	github.com/rustyoz/genericlexer v0.0.0-20190224115003-eb82fd2987bd // indirect
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.25.0 // indirect
 )
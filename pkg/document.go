@@ -0,0 +1,115 @@
+package spiffy
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// inkscapeNamespace is a namespace of inkscape:* attributes.
+const inkscapeNamespace = "http://www.inkscape.org/namespaces/inkscape"
+
+// element is a generic SVG XML element.
+type element struct {
+	Name     string
+	Attrs    map[string]string
+	Children []*element
+	Parent   *element
+}
+
+// Attr returns value of an attribute (empty string if not set).
+func (e *element) Attr(name string) string {
+	return e.Attrs[name]
+}
+
+// ID returns element's id attribute (or element name if not set) for logging purposes.
+func (e *element) ID() string {
+	if id := e.Attr("id"); id != "" {
+		return id
+	}
+
+	return "<" + e.Name + ">"
+}
+
+// Label returns element's inkscape:label (e.g. a name of an Inkscape layer).
+func (e *element) Label() string {
+	return e.Attr("inkscape:label")
+}
+
+// find returns the first element (in document order) with id or label equal to name.
+func (e *element) find(name string) *element {
+	if e.Attr("id") == name || e.Label() == name {
+		return e
+	}
+
+	for _, child := range e.Children {
+		if result := child.find(name); result != nil {
+			return result
+		}
+	}
+
+	return nil
+}
+
+// parseDocument decodes SVG XML into the element tree. Returned element is the root <svg> element.
+func parseDocument(data []byte) (*element, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var root, current *element
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if root != nil && current == nil {
+				break
+			}
+
+			return nil, fmt.Errorf("decoding SVG: %w", err)
+		}
+
+		switch tok := token.(type) {
+		case xml.StartElement:
+			e := &element{
+				Name:   tok.Name.Local,
+				Attrs:  make(map[string]string),
+				Parent: current,
+			}
+
+			for _, attr := range tok.Attr {
+				// keep inkscape:label (names of layers, see Label)
+				if attr.Name.Space == inkscapeNamespace && attr.Name.Local == "label" {
+					e.Attrs["inkscape:label"] = attr.Value
+					continue
+				}
+
+				// skip namespaced attributes (sodipodi:type etc.)
+				// except for xlink which may be important
+				if attr.Name.Space != "" && attr.Name.Space != "http://www.w3.org/1999/xlink" {
+					continue
+				}
+
+				e.Attrs[attr.Name.Local] = attr.Value
+			}
+
+			if current == nil {
+				if root != nil {
+					return nil, fmt.Errorf("decoding SVG: multiple root elements")
+				}
+
+				root = e
+			} else {
+				current.Children = append(current.Children, e)
+			}
+
+			current = e
+		case xml.EndElement:
+			current = current.Parent
+		}
+	}
+
+	if root.Name != "svg" {
+		return nil, fmt.Errorf("decoding SVG: root element is <%s>, expected <svg>", root.Name)
+	}
+
+	return root, nil
+}
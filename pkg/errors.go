@@ -0,0 +1,20 @@
+package spiffy
+
+import "errors"
+
+var (
+	ErrInvalidPathData  = errors.New("invalid SVG path data")
+	ErrInvalidTransform = errors.New("invalid SVG transform")
+	// ErrUnknownDocumentSize is returned if document needs to be scaled to a size but has neither width/height nor viewBox.
+	ErrUnknownDocumentSize = errors.New("document has no width/height nor viewBox")
+	// ErrInvalidWallAngle is returned if a wall angle (see WallProfile) is not in (0, 90] degrees.
+	ErrInvalidWallAngle = errors.New("wall angle must be greater than 0 and at most 90 degrees")
+	// ErrNoProfile is returned by Revolve if the document has no paths.
+	ErrNoProfile = errors.New("document has no profile to revolve")
+	// ErrNoOutline is returned by Loft if an outline can not be found.
+	ErrNoOutline = errors.New("no outline")
+	// ErrEmptyHeightmap is returned by Heightmap if the heightmap has no pixels.
+	ErrEmptyHeightmap = errors.New("heightmap is empty")
+	// ErrInvalidStepDown is returned by Revolve, Loft and Heightmap if step down (or depth) is not positive.
+	ErrInvalidStepDown = errors.New("step down must be positive")
+)
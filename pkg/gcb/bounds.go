@@ -0,0 +1,177 @@
+package gcb
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+	"github.com/gucio321/spiffy/pkg/workspace"
+)
+
+// OutOfBoundsError is returned when a point falls outside the workspace.
+// In collect mode (see CollectViolations) subsequent violations of the same
+// drawing call are merged into a single error and Region covers all of them.
+type OutOfBoundsError struct {
+	// Point is the (first) offending point in drawing coordinates (AbsolutePos).
+	Point BetterPoint[AbsolutePos]
+	// Axis is the axis ("X", "Y" or "XY" for merged violations) that exceeded the workspace.
+	Axis string
+	// Workspace is the workspace that was exceeded.
+	Workspace *workspace.Workspace
+	// Call is the builder's drawing call that caused the violation (e.g. "DrawLine").
+	Call string
+	// Caller is the file:line the drawing call was made from.
+	Caller string
+	// Region is the bounding box of all offending points (drawing coordinates).
+	Region geom.Rect
+	// Count is the number of offending points.
+	Count int
+}
+
+func (e *OutOfBoundsError) Error() string {
+	ws := e.Workspace
+	msg := fmt.Sprintf("%s: point %v exceeds workspace %q (X: 0 - %d, Y: 0 - %d) on %s axis",
+		e.Call, e.Point, ws.Name, ws.MaxX-ws.MinX, ws.MaxY-ws.MinY, e.Axis)
+
+	if e.Count > 1 {
+		msg += fmt.Sprintf(" (%d points in region (%.2f, %.2f) - (%.2f, %.2f))",
+			e.Count, e.Region.Min.X, e.Region.Min.Y, e.Region.Max.X, e.Region.Max.Y)
+	}
+
+	if e.Caller != "" {
+		msg += " called at " + e.Caller
+	}
+
+	return msg
+}
+
+func (e *OutOfBoundsError) Unwrap() error {
+	return ErrOutOfBounds
+}
+
+// OutOfBoundsErrors is a list of violations collected in collect mode (see CollectViolations).
+type OutOfBoundsErrors []*OutOfBoundsError
+
+func (e OutOfBoundsErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d workspace violations:\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+func (e OutOfBoundsErrors) Unwrap() []error {
+	result := make([]error, len(e))
+	for i, err := range e {
+		result[i] = err
+	}
+
+	return result
+}
+
+// CollectViolations enables/disables collect mode.
+// In collect mode, drawing continues if a point falls outside the workspace
+// and all violations are available via Violations.
+func (b *GCodeBuilder) CollectViolations(enabled bool) *GCodeBuilder {
+	b.collectViolations = enabled
+	return b
+}
+
+// Violations returns all workspace violations collected so far (see CollectViolations)
+// or nil if there were none.
+func (b *GCodeBuilder) Violations() error {
+	if len(b.violations) == 0 {
+		return nil
+	}
+
+	return b.violations
+}
+
+// validateHwAbs checks if p lies inside the workspace.
+// In collect mode it records the violation and returns nil.
+func (b *GCodeBuilder) validateHwAbs(p BetterPoint[HardwareAbsolutePos]) error {
+	b.validations++
+
+	axis := ""
+	switch {
+	case p.X < HardwareAbsolutePos(b.workspace.MinX), p.X > HardwareAbsolutePos(b.workspace.MaxX):
+		axis = "X"
+	case p.Y < HardwareAbsolutePos(b.workspace.MinY), p.Y > HardwareAbsolutePos(b.workspace.MaxY):
+		axis = "Y"
+	default:
+		return nil
+	}
+
+	absP := Redefine[AbsolutePos](p.Add(BetterPt(HardwareAbsolutePos(-b.workspace.MinX), HardwareAbsolutePos(-b.workspace.MinY))))
+	call, caller := drawingCall()
+
+	// 1.0: if the previous check was a violation in the same call, just extend it
+	if b.collectViolations && len(b.violations) > 0 && b.lastViolation == b.validations-1 {
+		last := b.violations[len(b.violations)-1]
+		if last.Call == call {
+			if last.Axis != axis {
+				last.Axis = "XY"
+			}
+
+			last.Region = last.Region.Extend(toGeom(absP))
+			last.Count++
+			b.lastViolation = b.validations
+
+			return nil
+		}
+	}
+
+	err := &OutOfBoundsError{
+		Point:     absP,
+		Axis:      axis,
+		Workspace: b.workspace,
+		Call:      call,
+		Caller:    caller,
+		Region:    geom.EmptyRect().Extend(toGeom(absP)),
+		Count:     1,
+	}
+
+	if !b.collectViolations {
+		return err
+	}
+
+	b.violations = append(b.violations, err)
+	b.lastViolation = b.validations
+
+	return nil
+}
+
+// gcbPackage is this package's prefix in function names (as reported by runtime).
+var gcbPackage = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	slash := strings.LastIndex(name, "/")
+
+	return name[:slash+strings.Index(name[slash:], ".")+1]
+}()
+
+// drawingCall finds the outermost builder method on the call stack
+// and the place it was called from.
+func drawingCall() (call, caller string) {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, gcbPackage) {
+			if call != "" {
+				caller = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+			}
+
+			return call, caller
+		}
+
+		call = strings.TrimPrefix(strings.TrimPrefix(frame.Function, gcbPackage), "(*GCodeBuilder).")
+
+		if !more {
+			return call, caller
+		}
+	}
+}
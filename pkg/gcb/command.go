@@ -1,35 +1,69 @@
 package gcb
 
 import (
-	"fmt"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 )
 
+// Command is a single line of GCode. If Code is empty, this is a comment-only line.
 type Command struct {
 	Code        GCode
 	Args        Args
 	LineComment string
+	// source is the line the command was read from (see CommandOf).
+	source *commandSource
 }
 
+// commandSource is a line read from a file and the command as it was read.
+type commandSource struct {
+	line    string
+	code    GCode
+	args    Args
+	comment string
+}
+
+// Source returns the line the command was read from (see CommandOf).
+// ok is false if the command was not read from a file or was changed since.
+func (c *Command) Source() (line string, ok bool) {
+	if c.source == nil || c.Code != c.source.code || c.LineComment != c.source.comment ||
+		!slices.Equal(c.Args, c.source.args) {
+		return "", false
+	}
+
+	return c.source.line, true
+}
+
+// String formats the command in the DefaultDialect (see Format).
 func (c *Command) String(line, above bool) string {
-	result := string(c.Code)
-	names := make([]string, 0, len(c.Args))
-	for name := range c.Args {
-		names = append(names, name)
+	return c.Format(DefaultDialect, line, above)
+}
+
+// Format formats the command using dialect's number format and arguments order.
+// line and above decide if line comments and comment-only commands are included
+// (pass markers are always included, so that Stats can find passes in G-code files).
+// Commands read from a file are written as they were read unless they were changed (see Source).
+func (c *Command) Format(d Dialect, line, above bool) string {
+	if source, ok := c.Source(); ok {
+		return source
 	}
 
-	// sort names
-	sort.Strings(names)
+	parts := []string{}
+	if c.Code != "" {
+		parts = append(parts, string(c.Code))
+	}
 
-	for _, name := range names {
-		result += fmt.Sprintf(" %v%f", name, c.Args[name])
+	for _, arg := range c.Args.ordered(d.ArgsOrder()) {
+		parts = append(parts, arg.Format(d))
 	}
 
+	result := strings.Join(parts, " ")
+
 	if c.LineComment != "" {
 		switch {
-		case (line && c.Code != "") || (above && c.Code == ""):
-			result += fmt.Sprintf(" ; %v", c.LineComment)
+		case (line && c.Code != "") || (above && c.Code == ""), c.isPassMarker():
+			result += " ; " + c.LineComment
 		}
 	}
 
@@ -46,4 +80,131 @@ func (c *Command) String(line, above bool) string {
 	return result
 }
 
-type Args map[string]RelativePos
+// isPassMarker returns true if the command is a pass marker (see BeginPass).
+func (c *Command) isPassMarker() bool {
+	return c.Code == "" && strings.HasPrefix(c.LineComment, PassMarker)
+}
+
+// ParamKind is a kind of command's argument.
+type ParamKind int
+
+const (
+	// ParamFloat is a number (e.g. X1.5).
+	ParamFloat ParamKind = iota
+	// ParamInt is an integer (e.g. S2000).
+	ParamInt
+	// ParamFlag is a letter without a value (e.g. X in G28 X Y).
+	ParamFlag
+	// ParamString is a text (e.g. Hello in M117 Hello). It has no name.
+	ParamString
+)
+
+// Param is a single argument of a command.
+type Param struct {
+	// Name is a letter of the argument (e.g. X). Empty for ParamString.
+	Name  string
+	Kind  ParamKind
+	Value float64
+	Text  string
+}
+
+// Arg creates a number argument.
+func Arg(name string, value RelativePos) Param {
+	return Param{Name: name, Kind: ParamFloat, Value: float64(value)}
+}
+
+// IntArg creates an integer argument.
+func IntArg(name string, value int) Param {
+	return Param{Name: name, Kind: ParamInt, Value: float64(value)}
+}
+
+// FlagArg creates an argument without a value.
+func FlagArg(name string) Param {
+	return Param{Name: name, Kind: ParamFlag}
+}
+
+// TextArg creates a text argument.
+func TextArg(text string) Param {
+	return Param{Kind: ParamString, Text: text}
+}
+
+// Format formats the argument using dialect's number format.
+func (p Param) Format(d Dialect) string {
+	switch p.Kind {
+	case ParamInt:
+		return p.Name + strconv.FormatInt(int64(p.Value), 10)
+	case ParamFlag:
+		return p.Name
+	case ParamString:
+		return p.Text
+	default:
+		return p.Name + d.FormatNumber(p.Value)
+	}
+}
+
+// Args are command's arguments in order.
+type Args []Param
+
+// Get returns value of the number argument.
+func (a Args) Get(name string) (RelativePos, bool) {
+	for _, p := range a {
+		if p.Name == name && (p.Kind == ParamFloat || p.Kind == ParamInt) {
+			return RelativePos(p.Value), true
+		}
+	}
+
+	return 0, false
+}
+
+// Value returns value of the number argument or 0 if there is no such an argument.
+func (a Args) Value(name string) RelativePos {
+	v, _ := a.Get(name)
+	return v
+}
+
+// Has returns true if there is an argument of the name (of any kind).
+func (a Args) Has(name string) bool {
+	for _, p := range a {
+		if p.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ordered returns arguments sorted by order of names in order (see Dialect.ArgsOrder).
+// Arguments not present in order keep their positions after the ordered ones.
+func (a Args) ordered(order string) Args {
+	rank := func(p Param) int {
+		if p.Name == "" {
+			return len(order) + 1
+		}
+
+		if i := strings.Index(order, p.Name); i >= 0 {
+			return i
+		}
+
+		return len(order)
+	}
+
+	result := make(Args, len(a))
+	copy(result, a)
+	sort.SliceStable(result, func(i, j int) bool {
+		return rank(result[i]) < rank(result[j])
+	})
+
+	return result
+}
+
+// formatFloat formats v with precision decimal places (-1 means the shortest exact representation).
+func formatFloat(v float64, precision int) string {
+	result := strconv.FormatFloat(v, 'f', precision, 32)
+
+	// don't write negative zeros
+	if strings.TrimLeft(result, "-0.") == "" {
+		result = strings.TrimPrefix(result, "-")
+	}
+
+	return result
+}
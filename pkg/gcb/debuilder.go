@@ -1,67 +1,147 @@
 package gcb
 
 import (
+	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/gucio321/spiffy/pkg/gcode"
 	"github.com/gucio321/spiffy/pkg/workspace"
-	"github.com/kpango/glg"
 )
 
-func NewGCodeBuilderFromGCode(gcode []byte) (*GCodeBuilder, error) {
+// NewGCodeBuilderFromGCode reads G-code (see gcode.Parse) into builder's commands.
+// Values are kept as written (units and positioning are not converted).
+// The builder is in absolute positioning mode, as a machine after reset (see gcode.NewState).
+// It has no default preamble nor postamble, so they are not added to the file (see SetPreamble).
+func NewGCodeBuilderFromGCode(data []byte) (*GCodeBuilder, error) {
 	workspace, err := workspace.Get(DefaultWorkspace)
 	if err != nil {
 		return nil, err
 	}
 
+	blocks, err := gcode.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
 	result := NewGCodeBuilder(workspace)
-	lines := strings.Split(string(gcode), "\n")
-	positioning := G90
-
-	for _, line := range lines {
-		splitted := strings.Split(line, ";")
-		command := splitted[0]
-		comment := strings.Join(splitted[1:], ";")
-
-		// trim unnecessary spaces from command
-		command = strings.TrimSpace(command)
-		sommandParts := strings.Split(command, " ")
-		code := GCode(sommandParts[0])
-
-		switch code {
-		case G90:
-			positioning = G90
-		case G91:
-			positioning = G91
-		default:
-			if positioning == G90 {
-				glg.Warnf("Got \"%s\" command but is in Relative Positioning mode which is not supported", code)
-				continue
-			}
+	result.AbsolutePositioning(gcode.NewState().Absolute)
+	result.wholeFile = true
+
+	for _, block := range blocks {
+		result.PushCommand(CommandOf(block))
+	}
+
+	return result, nil
+}
+
+// CommandOf converts a parsed line to a command which is written exactly as the line
+// as long as it is not changed (see Command.Source).
+// Code is the first code (G, M or T word) of the line or the modal motion code
+// for lines with arguments only. Other words are arguments in the order they are written.
+// Comments are joined.
+func CommandOf(block gcode.Block) Command {
+	result := Command{
+		LineComment: strings.Join(block.Comments, " "),
+	}
+
+	// 1.0: non-standard command
+	if block.Extended != "" {
+		result.Code = GCode(block.Extended)
+		if block.Text != "" {
+			result.Args = Args{TextArg(block.Text)}
 		}
 
-		args := make(map[string]RelativePos)
-		for _, arg := range sommandParts[1:] {
-			if len(arg) <= 1 {
-				continue
-			}
+		result.setSource(block.Raw)
+
+		return result
+	}
 
-			value, err := strconv.ParseFloat(arg[1:], 32)
-			if err != nil {
-				return nil, err
+	// 1.1: code
+	words := block.Words
+	switch codes := block.Codes(); {
+	case len(codes) > 0:
+		result.Code = GCode(codes[0].Code())
+		words = make([]gcode.Word, 0, len(block.Words)-1)
+		for _, w := range block.Words {
+			if w.Column != codes[0].Column {
+				words = append(words, w)
 			}
+		}
+	case len(words) > 0:
+		result.Code = GCode(block.Motion)
+	}
 
-			args[arg[0:1]] = RelativePos(value)
+	// 1.2: arguments
+	for _, w := range words {
+		var arg Param
+		switch {
+		case w.IsFlag():
+			arg = FlagArg(string(w.Letter))
+		case w.IsInt():
+			arg = IntArg(string(w.Letter), int(w.Value))
+		default:
+			arg = Arg(string(w.Letter), RelativePos(w.Value))
 		}
 
-		newCommand := Command{
-			Code:        code,
-			LineComment: comment,
-			Args:        args,
+		result.Args = append(result.Args, arg)
+	}
+
+	if block.Text != "" {
+		result.Args = append(result.Args, TextArg(block.Text))
+	}
+
+	result.setSource(block.Raw)
+
+	return result
+}
+
+// setSource remembers that the command is the line (see Command.Source).
+func (c *Command) setSource(line string) {
+	if line != "" {
+		c.source = &commandSource{line: line, code: c.Code, args: slices.Clone(c.Args), comment: c.LineComment}
+	}
+}
+
+// Block converts the command to a G-code block (see gcode.Machine).
+// Unchanged commands read from a file (see Source) are parsed again.
+func (c Command) Block() gcode.Block {
+	if source, ok := c.Source(); ok {
+		if result, err := gcode.ParseBlock(source); err == nil {
+			return result
+		}
+	}
+
+	// 1.0: code (which may contain more words, e.g. o1 repeat [2], see BeginLoop)
+	result, err := gcode.ParseBlock(string(c.Code))
+	if err != nil {
+		result = gcode.Block{Extended: string(c.Code)}
+	}
+
+	result.Comments = nil
+	if len(c.LineComment) > 0 {
+		result.Comments = []string{c.LineComment}
+	}
+
+	// 1.1: arguments
+	for _, arg := range c.Args {
+		w := gcode.Word{Value: arg.Value}
+		if len(arg.Name) > 0 {
+			w.Letter = arg.Name[0]
 		}
 
-		result.PushCommand(newCommand)
+		switch arg.Kind {
+		case ParamString:
+			result.Text = arg.Text
+			continue
+		case ParamInt:
+			w.Raw = strconv.Itoa(int(arg.Value))
+		case ParamFloat:
+			w.Raw = formatFloat(arg.Value, ShortestPrecision)
+		}
+
+		result.Words = append(result.Words, w)
 	}
 
-	return result, nil
+	return result
 }
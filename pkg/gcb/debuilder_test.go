@@ -0,0 +1,124 @@
+package gcb
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gucio321/spiffy/pkg/gcode"
+)
+
+func TestNewGCodeBuilderFromGCode_RoundTrip(t *testing.T) {
+	agh, err := os.ReadFile("../../cmd/justview/agh.gcode")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"agh.gcode", string(agh)},
+		{"compact", "G0X10Y5\ng1 x-1.50 y.5 f1200\n"},
+		{"multiple codes", "G90 G21 G1 X1 Y2 ; setup and move\nG91\n"},
+		{"line number and checksum", "N10 G1 X1.50 Y-2*29\n"},
+		{"comments", "(header)\nG0 X1 (first) Y2 ; second\n; only comment\n"},
+		{"text and extended", "M117 Hello World\nSET_VELOCITY_LIMIT ACCEL=2000\n"},
+		{"modal motion", "G1 X1\nX2 Y3\n"},
+		{"block delete", "/G0 X1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := NewGCodeBuilderFromGCode([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			lines := make([]string, len(b.commands))
+			for i, c := range b.commands {
+				lines[i] = c.Format(DefaultDialect, true, true)
+			}
+
+			if got := strings.Join(lines, "\n"); got != tt.data {
+				t.Errorf("round trip changed the file:\ngot:\n%s\nwant:\n%s", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestCommandOf(t *testing.T) {
+	tests := []struct {
+		line    string
+		code    GCode
+		args    string
+		comment string
+	}{
+		{"G0 X10 Y5", "G0", "X10 Y5", ""},
+		{"g01x1.5", "G1", "X1.500000", ""},
+		{"G90 G1 X1 ; move", "G90", "G1 X1", "move"},
+		{"G28 X Y", "G28", "X Y", ""},
+		{"M117 Hello", "M117", "Hello", ""},
+		{"; comment", "", "", "comment"},
+		{"SET_VELOCITY_LIMIT ACCEL=2000", "SET_VELOCITY_LIMIT", "ACCEL=2000", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			block, err := gcode.ParseBlock(tt.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			c := CommandOf(block)
+			args := make([]string, len(c.Args))
+			for i, arg := range c.Args {
+				args[i] = arg.Format(DefaultDialect)
+			}
+
+			if c.Code != tt.code || strings.Join(args, " ") != tt.args || c.LineComment != tt.comment {
+				t.Errorf("got %q %q ; %q, want %q %q ; %q",
+					c.Code, strings.Join(args, " "), c.LineComment, tt.code, tt.args, tt.comment)
+			}
+
+			if source, ok := c.Source(); !ok || source != tt.line {
+				t.Errorf("source is %q (%v), want %q", source, ok, tt.line)
+			}
+		})
+	}
+}
+
+func TestCommandOf_Edited(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		edit func(c *Command)
+		want string
+	}{
+		{"unchanged", "g1x1  y2 ;move", func(*Command) {}, "g1x1  y2 ;move"},
+		{"code", "G0 X1 Y2", func(c *Command) { c.Code = "G1" }, "G1 X1 Y2"},
+		{"argument", "G0 X10 Y5 ; go", func(c *Command) { c.Args[0].Value = 20 }, "G0 X20 Y5 ; go"},
+		{"new argument", "G1 X1", func(c *Command) { c.Args = append(c.Args, IntArg("F", 1000)) }, "G1 X1 F1000"},
+		{"comment", "G1 X1 (a)", func(c *Command) { c.LineComment = "b" }, "G1 X1 ; b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block, err := gcode.ParseBlock(tt.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			c := CommandOf(block)
+			tt.edit(&c)
+
+			if got := c.Format(DefaultDialect, true, true); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+
+			if got := c.Block().Words; len(got) == 0 || got[0].Code() != string(c.Code) {
+				t.Errorf("block does not follow the command: %v", got)
+			}
+		})
+	}
+}
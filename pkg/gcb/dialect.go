@@ -0,0 +1,260 @@
+package gcb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Feature is an optional firmware feature.
+type Feature int
+
+const (
+	// FeatureArcs is support for G2/G3 arc moves.
+	FeatureArcs Feature = iota
+	// FeatureBezierCubic is support for G5 cubic bezier moves.
+	FeatureBezierCubic
+	// FeatureLoops is support for repeating a block of commands (see (*GCodeBuilder).BeginLoop).
+	FeatureLoops
+)
+
+// Dialect describes differences between firmwares (G-Code flavours).
+type Dialect interface {
+	// Name is a dialect's name (e.g. "marlin").
+	Name() string
+	// MoveCode returns a code of linear move (travel or drawing).
+	MoveCode(drawing bool) GCode
+	// ArcCode returns a code of arc move.
+	ArcCode(clockwise bool) GCode
+	// Supports returns true if the firmware supports the feature.
+	Supports(f Feature) bool
+	// FormatNumber formats command's argument.
+	FormatNumber(v float64) string
+	// ArgsOrder returns letters of arguments in canonical order (e.g. "XYZF").
+	ArgsOrder() string
+	// Preamble returns a text/template (see TemplateData) of code executed before the job.
+	// If absolute is true, it should leave the machine in absolute (G90) mode and
+	// set current height as Z0. Otherwise it should switch to relative (G91) mode.
+	Preamble(absolute bool) string
+	// Postamble returns a text/template of code executed after the job.
+	Postamble() string
+	// Loop returns lines beginning and ending a block repeated n times.
+	// id is unique for every loop in the file.
+	// Used only if the dialect supports FeatureLoops.
+	Loop(id, n int) (begin, end string)
+}
+
+// dialect is a data-driven Dialect implementation.
+type dialect struct {
+	name               string
+	features           []Feature
+	precision          int
+	argsOrder          string
+	preamble           string
+	absolutePreamble   string
+	postamble          string
+	loopBegin, loopEnd string
+}
+
+var (
+	// Marlin is a dialect of Marlin firmware (default).
+	Marlin Dialect = &dialect{
+		name:             "marlin",
+		features:         []Feature{FeatureArcs},
+		precision:        6,
+		argsOrder:        "XYZEIJPQRF",
+		preamble:         DefaultPreamble,
+		absolutePreamble: DefaultAbsolutePreamble,
+		postamble:        DefaultPostamble,
+	}
+
+	// GRBL is a dialect of GRBL firmware.
+	GRBL Dialect = &dialect{
+		name:      "grbl",
+		features:  []Feature{FeatureArcs},
+		precision: 3,
+		argsOrder: "XYZIJRPF",
+		preamble: preambleHeader + `G21                ; Millimeters
+G17                ; XY plane
+G94 F{{if .Feeds.Draw}}{{.Feeds.Draw}}{{else}}1000.0{{end}} ; Feed rate in mm/min (G1 needs one)
+G90                ; Absolute positioning
+G0 X{{.Base.X}} Y{{.Base.Y}} ; Move to start position
+G91                ; Relative positioning
+;; END PREABUA
+
+;; BEGIN BUA
+`,
+		absolutePreamble: preambleHeader + `G21                ; Millimeters
+G17                ; XY plane
+G94 F{{if .Feeds.Draw}}{{.Feeds.Draw}}{{else}}1000.0{{end}} ; Feed rate in mm/min (G1 needs one)
+G90                ; Absolute positioning
+G0 X{{.Base.X}} Y{{.Base.Y}} ; Move to start position
+G92 Z0             ; Current height is Z0
+;; END PREABUA
+
+;; BEGIN BUA
+`,
+		postamble: `;; END BUA
+
+;; BEGIN POSTABUA
+M2 ; End of program
+;; END POSTABUA
+`,
+	}
+
+	// LinuxCNC is a dialect of LinuxCNC (rs274ngc).
+	LinuxCNC Dialect = &dialect{
+		name:      "linuxcnc",
+		features:  []Feature{FeatureArcs, FeatureBezierCubic, FeatureLoops},
+		precision: 4,
+		argsOrder: "XYZIJPQRF",
+		preamble: preambleHeader + `G21                ; Millimeters
+G17                ; XY plane
+G94 F{{if .Feeds.Draw}}{{.Feeds.Draw}}{{else}}1000.0{{end}} ; Feed rate in mm/min (G1 needs one)
+G90                ; Absolute positioning
+G0 X{{.Base.X}} Y{{.Base.Y}} ; Move to start position
+G91                ; Relative positioning
+;; END PREABUA
+
+;; BEGIN BUA
+`,
+		absolutePreamble: preambleHeader + `G21                ; Millimeters
+G17                ; XY plane
+G94 F{{if .Feeds.Draw}}{{.Feeds.Draw}}{{else}}1000.0{{end}} ; Feed rate in mm/min (G1 needs one)
+G90                ; Absolute positioning
+G0 X{{.Base.X}} Y{{.Base.Y}} ; Move to start position
+G92 Z0             ; Current height is Z0
+;; END PREABUA
+
+;; BEGIN BUA
+`,
+		postamble: `;; END BUA
+
+;; BEGIN POSTABUA
+M2 ; End of program
+;; END POSTABUA
+`,
+		loopBegin: "o%d repeat [%d]",
+		loopEnd:   "o%d endrepeat",
+	}
+
+	// Klipper is a dialect of Klipper firmware.
+	// NOTE: arcs require [gcode_arcs] section in printer.cfg.
+	Klipper Dialect = &dialect{
+		name:      "klipper",
+		features:  []Feature{FeatureArcs},
+		precision: 3,
+		argsOrder: "XYZEIJRF",
+		preamble: preambleHeader + `M107                            ; Fan off
+M104 S0                         ; Set target temperature
+G92 E0                          ; Hotend reset
+G90                             ; Absolute positioning
+G28 X Y                         ; Home X and Y axes
+G0 X{{.Base.X}} Y{{.Base.Y}} F{{if .Feeds.Travel}}{{.Feeds.Travel}}{{else}}5000.0{{end}} ; Move to start position
+G91                             ; Relative positioning
+SET_VELOCITY_LIMIT ACCEL=2000   ; Acceleration in mm/s/s
+;; END PREABUA
+
+;; BEGIN BUA
+`,
+		absolutePreamble: preambleHeader + `M107                            ; Fan off
+M104 S0                         ; Set target temperature
+G92 E0                          ; Hotend reset
+G90                             ; Absolute positioning
+G28 X Y                         ; Home X and Y axes
+G0 X{{.Base.X}} Y{{.Base.Y}} F{{if .Feeds.Travel}}{{.Feeds.Travel}}{{else}}5000.0{{end}} ; Move to start position
+G92 Z0                          ; Current height is Z0
+SET_VELOCITY_LIMIT ACCEL=2000   ; Acceleration in mm/s/s
+;; END PREABUA
+
+;; BEGIN BUA
+`,
+		postamble: DefaultPostamble,
+	}
+
+	// DefaultDialect is a dialect used by NewGCodeBuilder.
+	DefaultDialect = Marlin
+)
+
+// Dialects returns all known dialects.
+func Dialects() []Dialect {
+	return []Dialect{Marlin, GRBL, LinuxCNC, Klipper}
+}
+
+// DialectByName returns a dialect of the given name (case insensitive).
+func DialectByName(name string) (Dialect, error) {
+	for _, d := range Dialects() {
+		if strings.EqualFold(d.Name(), name) {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: %w", name, ErrUnknownDialect)
+}
+
+func (d *dialect) Name() string {
+	return d.name
+}
+
+func (d *dialect) MoveCode(drawing bool) GCode {
+	if drawing {
+		return GCodeDraw
+	}
+
+	return GCodeMove
+}
+
+func (d *dialect) ArcCode(clockwise bool) GCode {
+	if clockwise {
+		return GCodeArc
+	}
+
+	return GCodeArcCCW
+}
+
+func (d *dialect) Supports(f Feature) bool {
+	for _, feature := range d.features {
+		if feature == f {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *dialect) FormatNumber(v float64) string {
+	return formatFloat(v, d.precision)
+}
+
+func (d *dialect) ArgsOrder() string {
+	return d.argsOrder
+}
+
+// precisionDialect overrides dialect's number precision (see (*GCodeBuilder).SetPrecision).
+type precisionDialect struct {
+	Dialect
+	precision int
+}
+
+func (d precisionDialect) FormatNumber(v float64) string {
+	return formatFloat(v, d.precision)
+}
+
+func (d *dialect) Preamble(absolute bool) string {
+	if absolute {
+		return d.absolutePreamble
+	}
+
+	return d.preamble
+}
+
+func (d *dialect) Postamble() string {
+	return d.postamble
+}
+
+func (d *dialect) Loop(id, n int) (begin, end string) {
+	if d.loopBegin == "" {
+		return "", ""
+	}
+
+	return fmt.Sprintf(d.loopBegin, id, n), fmt.Sprintf(d.loopEnd, id)
+}
@@ -4,42 +4,98 @@ import (
 	"fmt"
 	"math"
 
-	"github.com/kpango/glg"
+	"github.com/gucio321/spiffy/pkg/geom"
 )
 
-// moveRel relative destination x, y.
-// NOTE: moveRel does NOT call Up/Down. It just moves.
-func (b *GCodeBuilder) moveRel(p BetterPoint[RelativePos]) *GCodeBuilder {
-	b.currentP = b.currentP.Add(Redefine[HardwareAbsolutePos](p))
+// moveTo moves to hardware absolute destination x, y.
+// NOTE: moveTo does NOT call Up/Down. It just moves.
+// While drawing it emits G1 with drawing feed, otherwise G0 with travel feed.
+// Zero-length moves are skipped.
+func (b *GCodeBuilder) moveTo(p BetterPoint[HardwareAbsolutePos]) *GCodeBuilder {
+	if p == b.currentP {
+		return b
+	}
+
+	args := b.coords(p)
+	b.currentP = p
+
+	// move may be too short to be seen by the machine
+	if !b.absolute && args.X == 0 && args.Y == 0 {
+		return b
+	}
+
+	feed := b.feeds.Travel
+	if b.isDrawing {
+		feed = b.feeds.Draw
+	}
 
 	// Push draw command
 	b.PushCommand(Command{
 		LineComment: fmt.Sprintf("Move to %v", b.currentP),
-		Code:        GCodeMove,
-		Args: map[string]RelativePos{
-			"X": p.X,
-			"Y": p.Y,
-		},
+		Code:        b.dialect.MoveCode(b.isDrawing),
+		Args: b.withFeed(Args{
+			Arg("X", args.X),
+			Arg("Y", args.Y),
+		}, feed),
 	})
 
-	b.validateHwAbs(b.currentP)
 	return b
 }
 
 // Move moves to absolute position given
-// NOTE: Move calls moveRel so does NOT call Up/Down. It just moves.
+// NOTE: Move calls moveTo so does NOT call Up/Down. It just moves.
+// If p is outside the workspace, *OutOfBoundsError is returned (see also CollectViolations).
 func (b *GCodeBuilder) Move(p BetterPoint[AbsolutePos]) error {
 	b.Commentf("BEGIN Move(%v)", p)
 
-	p = validateAbs(p)
-	relP := b.absToRel(b.translate(p))
-	b.moveRel(relP)
+	hwAbsP := b.translate(p)
+	if err := b.validateHwAbs(hwAbsP); err != nil {
+		return fmt.Errorf("cant move: %w", err)
+	}
+
+	b.moveTo(hwAbsP)
 
 	b.Commentf("END Move(%v)", p)
 
 	return nil
 }
 
+// Ramp moves to p while moving the head by dz along Z axis (e.g. to descend gradually while drawing).
+// NOTE: Ramp does NOT call Up/Down. It draws if the head is down.
+// If p is outside the workspace, *OutOfBoundsError is returned (see also CollectViolations).
+func (b *GCodeBuilder) Ramp(p BetterPoint[AbsolutePos], dz RelativePos) error {
+	hwAbsP := b.translate(p)
+	if err := b.validateHwAbs(hwAbsP); err != nil {
+		return fmt.Errorf("cant ramp: %w", err)
+	}
+
+	args := b.coords(hwAbsP)
+	b.currentP = hwAbsP
+	z := b.moveZ(dz)
+
+	// move may be too short to be seen by the machine
+	if !b.absolute && args.X == 0 && args.Y == 0 && z == 0 {
+		return nil
+	}
+
+	feed := b.feeds.Travel
+	if b.isDrawing {
+		feed = b.feeds.Draw
+	}
+
+	b.PushCommand(Command{
+		LineComment: fmt.Sprintf("Ramp to %v", b.currentP),
+		Code:        b.dialect.MoveCode(b.isDrawing),
+		Args: b.withFeed(Args{
+			Arg("X", args.X),
+			Arg("Y", args.Y),
+			Arg("Z", z),
+		}, feed),
+	})
+
+	return nil
+}
+
 // Comment writes comment to GCode.
 func (b *GCodeBuilder) Comment(comment string) *GCodeBuilder {
 	b.PushCommand(Command{
@@ -69,7 +125,10 @@ func (b *GCodeBuilder) DrawLine(p0, p1 BetterPoint[AbsolutePos]) error {
 	}
 
 	// 1.2: go to x1, y1
-	b.Move(p1)
+	if err := b.Move(p1); err != nil {
+		return fmt.Errorf("cant draw line: %w", err)
+	}
+
 	// 1.3: stop drawing
 	if err := b.stopDrawing(); err != nil {
 		return fmt.Errorf("cant stop drawing line: %w", err)
@@ -91,7 +150,9 @@ func (b *GCodeBuilder) DrawLines(path ...BetterPoint[AbsolutePos]) error {
 	for i := 1; i < len(path); i++ {
 		b.Commentf("Line %d", i)
 		p0 := path[i]
-		b.Move(p0)
+		if err := b.Move(p0); err != nil {
+			return fmt.Errorf("cant draw lines: %w", err)
+		}
 	}
 
 	if err := b.stopDrawing(); err != nil {
@@ -108,29 +169,14 @@ func (b *GCodeBuilder) DrawCircle(pImg BetterPoint[AbsolutePos], r float32) erro
 	b.Commentf("BEGIN DrawCircle(%f, %f)", pImg, r)
 
 	// 1.0: find x,y to move
-	p := b.translate(pImg)
 	baseP := BetterPoint[AbsolutePos]{
 		X: pImg.X,
 		Y: pImg.Y + AbsolutePos(r),
 	}
 
-	if err := b.startDrawing(baseP); err != nil {
-		return fmt.Errorf("cant start drawing circle: %w", err)
-	}
-
 	// 1.1: do circle
-	relP := b.absToRel(p)
-	b.PushCommand(Command{
-		LineComment: fmt.Sprintf("Draw circle with center in %v Ands at %v", relP, baseP),
-		Code:        GCodeArc,
-		Args: Args{
-			"I": relP.X,
-			"J": relP.Y,
-		},
-	})
-
-	if err := b.stopDrawing(); err != nil {
-		return fmt.Errorf("cant stop drawing circle: %w", err)
+	if err := b.DrawArc(baseP, baseP, pImg, true); err != nil {
+		return fmt.Errorf("cant draw circle: %w", err)
 	}
 
 	b.Commentf("END DrawCircle(%f, %f)", pImg, r)
@@ -140,16 +186,18 @@ func (b *GCodeBuilder) DrawCircle(pImg BetterPoint[AbsolutePos], r float32) erro
 
 // DrawCircleFilled draws a filled circle.
 // Make sure to set headSize before.
-func (b *GCodeBuilder) DrawCircleFilled(p BetterPoint[AbsolutePos], radius float32) *GCodeBuilder {
+func (b *GCodeBuilder) DrawCircleFilled(p BetterPoint[AbsolutePos], radius float32) error {
 	b.Commentf("BEGIN DrawCircleFilled(%v, %f)", p, radius)
 
 	for r := radius; r > 0; r -= float32(b.headSize) {
-		b.DrawCircle(p, r)
+		if err := b.DrawCircle(p, r); err != nil {
+			return fmt.Errorf("cant draw filled circle: %w", err)
+		}
 	}
 
 	b.Commentf("END DrawCircleFilled(%f, %f)", p, radius)
 
-	return b
+	return nil
 }
 
 // DrawSector draws a sector (part of circle) on absolute (x,y) with radius r.
@@ -158,47 +206,86 @@ func (b *GCodeBuilder) DrawCircleFilled(p BetterPoint[AbsolutePos], radius float
 func (b *GCodeBuilder) DrawSector(pImg BetterPoint[AbsolutePos], radius float32, start, end float32) error {
 	b.Commentf("BEGIN DrawSector(%v, %f, %f, %f)", pImg, radius, start, end)
 
-	// 1.0: find x,y to move
-	p := b.translate(pImg)
+	// 1.0: find start x,y
 	baseP := pImg.Add(BetterPoint[AbsolutePos]{
 		AbsolutePos(math.Cos(float64(start)) * float64(radius)),
 		AbsolutePos(math.Sin(float64(start)) * float64(radius)),
 	})
 
-	if err := b.startDrawing(baseP); err != nil {
-		return fmt.Errorf("cant start drawing sector: %w", err)
-	}
-
 	// 1.1: find final x,y
 	finalP := pImg.Add(BetterPoint[AbsolutePos]{
 		AbsolutePos(math.Cos(float64(end)) * float64(radius)),
 		AbsolutePos(math.Sin(float64(end)) * float64(radius)),
 	})
 
-	hwAbsFinalP := b.translate(finalP)
+	// 1.2: do arc
+	if err := b.DrawArc(baseP, finalP, pImg, false); err != nil {
+		return fmt.Errorf("cant draw sector: %w", err)
+	}
 
-	relFinalP := b.absToRel(b.translate(finalP))
-	// 1.2: do circle
-	relP := b.absToRel(p)
+	b.Commentf("END DrawSector(%v, %f, %f, %f)", pImg, radius, start, end)
 
-	b.PushCommand(Command{
-		LineComment: fmt.Sprintf("Draw sector with center in %v Ends at %v", relP, hwAbsFinalP),
-		Code:        GCodeArc,
-		Args: Args{
-			"I": relP.X,
-			"J": relP.Y,
-			"X": relFinalP.X,
-			"Y": relFinalP.Y,
-		},
-	})
+	return nil
+}
 
-	b.currentP = hwAbsFinalP
+// DrawArc draws a circular arc from start to end around center.
+// If start == end, full circle is drawn.
+// If native arcs are enabled (see NativeArcs) and supported by the dialect it uses G2/G3 commands.
+// Otherwise the arc is approximated with lines (see SetTolerance).
+func (b *GCodeBuilder) DrawArc(start, end, center BetterPoint[AbsolutePos], clockwise bool) error {
+	b.Commentf("BEGIN DrawArc(%v, %v, %v, %v)", start, end, center, clockwise)
+
+	if err := b.startDrawing(start); err != nil {
+		return fmt.Errorf("cant start drawing arc: %w", err)
+	}
+
+	if b.nativeArcs && b.dialect.Supports(FeatureArcs) {
+		// 1.0: arc may leave the workspace even if both ends are inside
+		arc := geom.CircularArc(toGeom(center), toGeom(start), toGeom(end), clockwise)
+		for _, p := range arc.Flatten(b.tolerance)[1:] {
+			if err := b.validateHwAbs(b.translate(fromGeom(p))); err != nil {
+				return fmt.Errorf("cant draw arc: %w", err)
+			}
+		}
+
+		// 1.1: I, J is a center relative to the start point (where the machine really is)
+		relCenter := Redefine[RelativePos](b.translate(center).Add(b.machinePos().Mul(-1)))
+		hwAbsEnd := b.translate(end)
+		relEnd := b.coords(hwAbsEnd)
+
+		b.PushCommand(Command{
+			LineComment: fmt.Sprintf("Draw arc with center in %v Ends at %v", relCenter, hwAbsEnd),
+			Code:        b.dialect.ArcCode(clockwise),
+			Args: b.withFeed(Args{
+				Arg("I", relCenter.X),
+				Arg("J", relCenter.Y),
+				Arg("X", relEnd.X),
+				Arg("Y", relEnd.Y),
+			}, b.feeds.Draw),
+		})
+
+		b.currentP = hwAbsEnd
+	} else {
+		// 1.0: approximate with lines
+		arc := geom.CircularArc(toGeom(center), toGeom(start), toGeom(end), clockwise)
+		points := arc.Flatten(b.tolerance)
+		for _, p := range points[1 : len(points)-1] {
+			if err := b.Move(fromGeom(p)); err != nil {
+				return fmt.Errorf("cant draw arc: %w", err)
+			}
+		}
+
+		// 1.1: make sure we finish exactly at the end point
+		if err := b.Move(end); err != nil {
+			return fmt.Errorf("cant draw arc: %w", err)
+		}
+	}
 
 	if err := b.stopDrawing(); err != nil {
-		return fmt.Errorf("cant stop drawing sector: %w", err)
+		return fmt.Errorf("cant stop drawing arc: %w", err)
 	}
 
-	b.Commentf("END DrawSector(%v, %f, %f, %f)", p, radius, start, end)
+	b.Commentf("END DrawArc(%v, %v, %v, %v)", start, end, center, clockwise)
 
 	return nil
 }
@@ -211,10 +298,11 @@ func (b *GCodeBuilder) DrawRect(p0, p1 BetterPoint[AbsolutePos]) error {
 		return fmt.Errorf("cant start drawing rect: %w", err)
 	}
 
-	b.Move(p0.Add(BetterPt(p1.X, 0)))
-	b.Move(p1)
-	b.Move(p0.Add(BetterPt(0, p1.Y)))
-	b.Move(p0)
+	for _, p := range []BetterPoint[AbsolutePos]{p0.Add(BetterPt(p1.X, 0)), p1, p0.Add(BetterPt(0, p1.Y)), p0} {
+		if err := b.Move(p); err != nil {
+			return fmt.Errorf("cant draw rect: %w", err)
+		}
+	}
 
 	if err := b.stopDrawing(); err != nil {
 		return fmt.Errorf("cant stop drawing rect: %w", err)
@@ -226,17 +314,19 @@ func (b *GCodeBuilder) DrawRect(p0, p1 BetterPoint[AbsolutePos]) error {
 }
 
 // DrawRectFilled draws a filled rectangle.
-func (b *GCodeBuilder) DrawRectFilled(p0, p1 BetterPoint[AbsolutePos]) *GCodeBuilder {
+func (b *GCodeBuilder) DrawRectFilled(p0, p1 BetterPoint[AbsolutePos]) error {
 	b.Commentf("BEGIN DrawRectFilled(%v, %v)", p0, p1)
 
 	delta := AbsolutePos(b.headSize)
 	for p00, p11 := p0, p1; p00.X < p11.X || p00.Y < p11.Y; p00.X, p00.Y, p11.X, p11.Y = p00.X+delta, p00.Y+delta, p11.X-delta, p11.Y-delta {
-		b.DrawRect(p00, p11)
+		if err := b.DrawRect(p00, p11); err != nil {
+			return fmt.Errorf("cant draw filled rect: %w", err)
+		}
 	}
 
 	b.Commentf("END DrawRectFilled(%v, %v)", p0, p1)
 
-	return b
+	return nil
 }
 
 // DrawBezierCubic draws a... Bezier cubic.
@@ -247,8 +337,13 @@ func (b *GCodeBuilder) DrawRectFilled(p0, p1 BetterPoint[AbsolutePos]) *GCodeBui
 * - P and Q: relative offset from end to 2nd control pt
 * - X and Y: end point
  */
+// G5 is not supported by most 3D printers (https://github.com/gucio321/spiffy/issues/1),
+// so if the dialect does not support FeatureBezierCubic, it falls back to DrawBezierAdaptive.
 func (b *GCodeBuilder) DrawBezierCubic(start, end, control1, control2 BetterPoint[AbsolutePos]) error {
-	glg.Fatal("DrawBezierCubic will not work on 3D printers. Use DrawBezier. https://github.com/gucio321/spiffy/issues/1. If you know you really want to use this... you have a problem hehe.")
+	if !b.dialect.Supports(FeatureBezierCubic) {
+		return b.DrawBezierAdaptive(start, control1, control2, end)
+	}
+
 	b.Commentf("BEGIN DrawBezierCubic(%v, %v, %v, %v)", start, end, control1, control2)
 
 	// 1.0: move to start and start drawing
@@ -256,26 +351,34 @@ func (b *GCodeBuilder) DrawBezierCubic(start, end, control1, control2 BetterPoin
 		return fmt.Errorf("cant start drawing cubic bezier: %w", err)
 	}
 
-	// 1.2: calculate control point 1 (as relative to start)
-	control1Rel := b.absToRel(b.translate(control1))
-	// 1.3: find relative end pos
-	endRel := b.absToRel(b.translate(end))
+	// 1.1: control points may bulge the curve out of the workspace even if both ends are inside
+	for _, p := range geom.FlattenBezier(b.tolerance, toGeom(start), toGeom(control1), toGeom(control2), toGeom(end))[1:] {
+		if err := b.validateHwAbs(b.translate(fromGeom(p))); err != nil {
+			return fmt.Errorf("cant draw cubic bezier: %w", err)
+		}
+	}
+
+	// 1.2: calculate control point 1 (as relative to the start, where the machine really is)
+	endHwAbs := b.translate(end)
+	control1Rel := Redefine[RelativePos](b.translate(control1).Add(b.machinePos().Mul(-1)))
+	// 1.3: find relative (or absolute) end pos
+	endRel := b.coords(endHwAbs)
 	// 1.4: calculate control point 2 (as relative to end)
 	// according to doc it should be control2-end
 	control2Rel := Redefine[RelativePos](control2.Add(end.Mul(-1)))
+
 	// 1.5: draw
-	endHwAbs := b.translate(end)
 	b.PushCommand(Command{
 		LineComment: fmt.Sprintf("Finish at %v", endHwAbs),
 		Code:        GCodeBezierCubic,
-		Args: Args{
-			"I": control1Rel.X,
-			"J": control1Rel.Y,
-			"P": control2Rel.X,
-			"Q": control2Rel.Y,
-			"X": endRel.X,
-			"Y": endRel.Y,
-		},
+		Args: b.withFeed(Args{
+			Arg("I", control1Rel.X),
+			Arg("J", control1Rel.Y),
+			Arg("P", control2Rel.X),
+			Arg("Q", control2Rel.Y),
+			Arg("X", endRel.X),
+			Arg("Y", endRel.Y),
+		}, b.feeds.Draw),
 	})
 
 	// 1.6: stop drawing
@@ -293,18 +396,50 @@ func (b *GCodeBuilder) DrawBezierCubic(start, end, control1, control2 BetterPoin
 // DrawBezier draws a bezier but does it better than DrawBezierCubic.
 // Generally refer to https://github.com/gucio321/spiffy/issues/1 for reasons why you dont' want to use the previous fn.
 // This one will use `G0` GCode commands (or if you preffer: (*GCodeBuilder).Move()) to draw a bezier-like line.
-// steps is an "accuracy" measure. It describes how many points will be calculated.
+// steps is an "accuracy" measure. It describes how many lines will be drawn.
+// See also DrawBezierAdaptive.
 func (b *GCodeBuilder) DrawBezier(steps uint, points ...BetterPoint[AbsolutePos]) error {
 	if err := b.startDrawing(points[0]); err != nil {
-		return fmt.Errorf("cant start drawing line: %w", err)
+		return fmt.Errorf("cant start drawing bezier: %w", err)
+	}
+
+	for i := uint(1); i < steps; i++ {
+		if err := b.Move(bezier(float64(i)/float64(steps), points)); err != nil {
+			return fmt.Errorf("cant draw bezier: %w", err)
+		}
+	}
+
+	if err := b.Move(points[len(points)-1]); err != nil {
+		return fmt.Errorf("cant draw bezier: %w", err)
+	}
+
+	if err := b.stopDrawing(); err != nil {
+		return fmt.Errorf("cant stop drawing bezier: %w", err)
+	}
+
+	return nil
+}
+
+// DrawBezierAdaptive draws a bezier curve (of any degree) with lines.
+// Number of lines depends on the curve's shape: it is subdivided until
+// the distance between the curve and the lines is within Tolerance().
+func (b *GCodeBuilder) DrawBezierAdaptive(points ...BetterPoint[AbsolutePos]) error {
+	if err := b.startDrawing(points[0]); err != nil {
+		return fmt.Errorf("cant start drawing bezier: %w", err)
 	}
 
-	for t := float32(0); t <= 1; t += 1 / float32(steps) {
-		if err := b.Move(bezier(t, points)); err != nil {
-			return fmt.Errorf("cant start drawing bezier: %w", err)
+	flattened := geom.FlattenBezier(b.tolerance, toGeomSlice(points)...)
+	for _, p := range flattened[1 : len(flattened)-1] {
+		if err := b.Move(fromGeom(p)); err != nil {
+			return fmt.Errorf("cant draw bezier: %w", err)
 		}
 	}
 
+	// make sure we finish exactly at the end point
+	if err := b.Move(points[len(points)-1]); err != nil {
+		return fmt.Errorf("cant draw bezier: %w", err)
+	}
+
 	if err := b.stopDrawing(); err != nil {
 		return fmt.Errorf("cant stop drawing bezier: %w", err)
 	}
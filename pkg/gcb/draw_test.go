@@ -0,0 +1,81 @@
+package gcb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gucio321/spiffy/pkg/workspace"
+)
+
+func TestGCodeBuilder_DrawBezierCubic(t *testing.T) {
+	pt := BetterPt[AbsolutePos]
+	tests := []struct {
+		name               string
+		start, c1, c2, end BetterPoint[AbsolutePos]
+		err                error
+	}{
+		{"inside", pt(10, 10), pt(20, 50), pt(40, 50), pt(50, 10), nil},
+		{"control points bulge out", pt(10, 10), pt(20, -40), pt(40, -40), pt(50, 10), ErrOutOfBounds},
+		{"end out", pt(10, 10), pt(20, 50), pt(40, 50), pt(500, 10), ErrOutOfBounds},
+	}
+
+	ws, err := workspace.Get(DefaultWorkspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewGCodeBuilder(ws).SetDialect(LinuxCNC)
+			if err := b.DrawBezierCubic(tt.start, tt.end, tt.c1, tt.c2); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			if tt.err != nil {
+				return
+			}
+
+			for _, c := range b.Commands() {
+				if c.Code != GCodeBezierCubic {
+					continue
+				}
+
+				// I, J is the first control point relative to the start
+				if i, j := c.Args.Value("I"), c.Args.Value("J"); i != 10 || j != 40 {
+					t.Errorf("I, J are %v, %v, want 10, 40", i, j)
+				}
+
+				return
+			}
+
+			t.Error("no G5 command")
+		})
+	}
+}
+
+func TestGCodeBuilder_SetTolerance(t *testing.T) {
+	tests := []struct {
+		name      string
+		tolerance float64
+		want      float64
+	}{
+		{"positive", 0.1, 0.1},
+		{"minimal", MinTolerance, MinTolerance},
+		{"too small", MinTolerance / 10, MinTolerance},
+		{"zero", 0, MinTolerance},
+		{"negative", -1, MinTolerance},
+	}
+
+	ws, err := workspace.Get(DefaultWorkspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewGCodeBuilder(ws).SetTolerance(tt.tolerance).Tolerance(); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
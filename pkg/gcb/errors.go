@@ -5,4 +5,7 @@ import "errors"
 var (
 	ErrCantChangeDrawingState           = errors.New("cannot change drawing state")
 	ErrInvalidContinousLineContinuation = errors.New("invalid continous line continuation - current position does not match estimated start position.")
+	ErrOutOfBounds                      = errors.New("position out of workspace bounds")
+	ErrUnknownDialect                   = errors.New("unknown dialect")
+	ErrUnsupportedFeature               = errors.New("feature not supported by the dialect")
 )
@@ -3,8 +3,7 @@ package gcb
 
 import (
 	"fmt"
-	"runtime"
-	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/gucio321/spiffy/pkg/workspace"
@@ -23,14 +22,22 @@ type (
 	HardwareAbsolutePos float32
 )
 
-const DefaultPreamble = ` ; BEGIN PREAMBUA
-M413 S0            ; Disable power loss recovery
+// preambleHeader begins all default preambles.
+const preambleHeader = ` ; BEGIN PREAMBUA
+{{- if .JobName}}
+ ; Job: {{.JobName}}
+{{- end}}
+ ; Estimated time: {{.EstimatedTime}}
+`
+
+// DefaultPreamble is Marlin's preamble. It is a text/template (see TemplateData).
+const DefaultPreamble = preambleHeader + `M413 S0            ; Disable power loss recovery
 M107               ; Fan off
 M104 S0            ; Set target temperature
 G92 E0             ; Hotend reset
 G90                ; Absolute positioning
 G28 X Y            ; Home X and Y axes
-G0 X80 Y80 F5000.0 ; Move to start position
+G0 X{{.Base.X}} Y{{.Base.Y}} F{{if .Feeds.Travel}}{{.Feeds.Travel}}{{else}}5000.0{{end}} ; Move to start position
 G91                ; Relative positioning
 M204 S2000         ; PRinting and travel speed in mm/s/s
 ;; END PREABUA
@@ -38,6 +45,23 @@ M204 S2000         ; PRinting and travel speed in mm/s/s
 ;; BEGIN BUA
 `
 
+// DefaultAbsolutePreamble is DefaultPreamble for absolute positioning (see AbsolutePositioning).
+// Z is not homed, so starting height becomes Z0.
+const DefaultAbsolutePreamble = preambleHeader + `M413 S0            ; Disable power loss recovery
+M107               ; Fan off
+M104 S0            ; Set target temperature
+G92 E0             ; Hotend reset
+G90                ; Absolute positioning
+G28 X Y            ; Home X and Y axes
+G0 X{{.Base.X}} Y{{.Base.Y}} F{{if .Feeds.Travel}}{{.Feeds.Travel}}{{else}}5000.0{{end}} ; Move to start position
+G92 Z0             ; Current height is Z0
+M204 S2000         ; PRinting and travel speed in mm/s/s
+;; END PREABUA
+
+;; BEGIN BUA
+`
+
+// DefaultPostamble is Marlin's postamble. It is a text/template (see TemplateData).
 const DefaultPostamble = `;; END BUA
 
 ;; BEGIN POSTABUA
@@ -50,39 +74,94 @@ var BaseDepth RelativePos = 26
 const (
 	DefaultWorkspace = "default"
 	DefaultHeadSize  = 2
-	// BaseX, BaseY are base coordinates for the printer.
-	BaseX, BaseY = 80, 80 // this is from "so called" PREAMBUŁA
+	// DefaultTolerance is a default max distance (in mm) between a curve and its approximation.
+	DefaultTolerance = 0.05
+	// MinTolerance is the smallest tolerance (see SetTolerance). Smaller ones would make
+	// approximations of curves huge for no precision a machine could reproduce.
+	MinTolerance = 0.001
+	// DialectPrecision makes builder format numbers as its dialect does (see SetPrecision).
+	DialectPrecision = -2
+	// ShortestPrecision formats numbers with as few digits as necessary to represent them exactly.
+	ShortestPrecision = -1
+	// BaseX, BaseY are default base coordinates for the printer (see (*workspace.Workspace).Base).
+	BaseX, BaseY = workspace.DefaultBaseX, workspace.DefaultBaseY
 )
 
+// Feeds are feed rates (in mm/min) of the builder's moves.
+// 0 means that F is not emitted (so the machine uses the last one).
+// Preambles of dialects which refuse G1 without a feed (GRBL, LinuxCNC) set Draw
+// (or a default one) before the job.
+type Feeds struct {
+	// Draw is a feed of drawing (G1, G2, G3) moves.
+	Draw float32
+	// Travel is a feed of travel (G0) moves (including going up).
+	Travel float32
+	// Plunge is a feed of going down (see Down and ShiftZ).
+	Plunge float32
+}
+
 // GCodeBuilder allows to build GCode. It implements several drawing methods.
 // Its purpose is to convert SVG image to GCode in an easy way. (see (*Spiffy).GCode).
 // NOTE: even considering the comment on HardwareAbsolutePos, all external API for this object
 // uses AbsolutePos - position absolute to image you want to draw (so starting from 0,0)
 type GCodeBuilder struct {
-	workspace           *workspace.Workspace
-	lineComments        bool
-	commentsAbove       bool
-	commands            []Command
-	depth               RelativePos
-	headSize            int
-	isDrawing           bool
-	currentP            BetterPoint[HardwareAbsolutePos]
+	workspace     *workspace.Workspace
+	lineComments  bool
+	commentsAbove bool
+	// commentColumn is a column of line comments (see AlignComments)
+	commentColumn int
+	commands      []Command
+	depth         RelativePos
+	headSize      int
+	isDrawing     bool
+	currentP      BetterPoint[HardwareAbsolutePos]
+	// machineP is where the machine is after rounded relative moves (see coords)
+	machineP      BetterPoint[HardwareAbsolutePos]
+	continousLine bool
+	// preamble and postamble are templates (see SetPreamble). Empty means dialect's default.
 	preamble, postamble string
-	continousLine       bool
+	jobName             string
+	nativeArcs          bool
+	tolerance           float64
+	dialect             Dialect
+	precision           int
+	collectViolations   bool
+	violations          OutOfBoundsErrors
+	absolute            bool
+	feeds               Feeds
+	// feed is the last emitted feed
+	feed float32
+	// loops is a stack of open loops' ids (see BeginLoop)
+	loops  []int
+	loopID int
+	// z is current Z relative to the starting height (see ShiftZ)
+	z RelativePos
+	// machineZ is where the machine is after rounded relative Z moves (see moveZ)
+	machineZ RelativePos
+	// validations counts bounds checks. Used to merge subsequent violations.
+	validations, lastViolation int
+	// wholeFile is true if commands are a whole file with its own preamble and postamble
+	// (see NewGCodeBuilderFromGCode). Then the default ones are empty.
+	wholeFile bool
 }
 
 // NewGCodeBuilder creates new GCodeBuilder with default values.
 func NewGCodeBuilder(workspace *workspace.Workspace) *GCodeBuilder {
+	baseX, baseY := workspace.Base()
+
 	return &GCodeBuilder{
 		workspace:     workspace,
 		lineComments:  true,
 		commentsAbove: false,
-		currentP:      BetterPoint[HardwareAbsolutePos]{BaseX, BaseY},
+		currentP:      BetterPt(HardwareAbsolutePos(baseX), HardwareAbsolutePos(baseY)),
+		machineP:      BetterPt(HardwareAbsolutePos(baseX), HardwareAbsolutePos(baseY)),
 		depth:         BaseDepth,
 		headSize:      DefaultHeadSize,
-		preamble:      DefaultPreamble,
-		postamble:     DefaultPostamble,
+		dialect:       DefaultDialect,
+		precision:     DialectPrecision,
 		continousLine: false,
+		nativeArcs:    workspace.NativeArcs,
+		tolerance:     DefaultTolerance,
 	}
 }
 
@@ -97,6 +176,13 @@ func (b *GCodeBuilder) Comments(line, above bool) *GCodeBuilder {
 	return b
 }
 
+// AlignComments sets column of line comments: AlignToLongest (default), NoAlignment
+// or a fixed column (lines longer than that are not aligned).
+func (b *GCodeBuilder) AlignComments(column int) *GCodeBuilder {
+	b.commentColumn = column
+	return b
+}
+
 // SetDepth sets how deep the Heas should go.
 func (b *GCodeBuilder) SetDepth(depth RelativePos) *GCodeBuilder {
 	b.depth = depth
@@ -109,8 +195,95 @@ func (b *GCodeBuilder) SetHeadSize(size int) *GCodeBuilder {
 	return b
 }
 
+// NativeArcs enables/disables G2/G3 arc moves. If disabled, arcs are approximated with lines.
+func (b *GCodeBuilder) NativeArcs(enabled bool) *GCodeBuilder {
+	b.nativeArcs = enabled
+	return b
+}
+
+// SetTolerance sets max distance (in mm) between a curve and lines approximating it.
+// Tolerances smaller than MinTolerance (including zero and negative ones) are replaced by MinTolerance.
+func (b *GCodeBuilder) SetTolerance(tolerance float64) *GCodeBuilder {
+	b.tolerance = max(tolerance, MinTolerance)
+	return b
+}
+
+// AbsolutePositioning enables/disables absolute (G90) output.
+// Then X/Y are machine coordinates (HardwareAbsolutePos) and Z is relative to the starting height.
+// NOTE: preamble is switched accordingly (see Dialect.Preamble).
+func (b *GCodeBuilder) AbsolutePositioning(enabled bool) *GCodeBuilder {
+	b.absolute = enabled
+	return b
+}
+
+// IsAbsolute returns true if builder emits absolute (G90) coordinates.
+func (b *GCodeBuilder) IsAbsolute() bool {
+	return b.absolute
+}
+
+// SetDialect sets firmware dialect (codes, number format, preamble and postamble).
+func (b *GCodeBuilder) SetDialect(d Dialect) *GCodeBuilder {
+	b.dialect = d
+	return b
+}
+
+// Dialect returns builder's dialect.
+func (b *GCodeBuilder) Dialect() Dialect {
+	return b.dialect
+}
+
+// SetPrecision sets number of decimal places of numbers in the output.
+// Use DialectPrecision (default) for dialect's format or ShortestPrecision for exact values.
+func (b *GCodeBuilder) SetPrecision(precision int) *GCodeBuilder {
+	b.precision = precision
+	return b
+}
+
+// outputDialect returns builder's dialect with number precision applied.
+func (b *GCodeBuilder) outputDialect() Dialect {
+	if b.precision == DialectPrecision {
+		return b.dialect
+	}
+
+	return precisionDialect{b.dialect, b.precision}
+}
+
+// SetFeeds sets feed rates of drawing, travel and plunge moves.
+func (b *GCodeBuilder) SetFeeds(feeds Feeds) *GCodeBuilder {
+	b.feeds = feeds
+	return b
+}
+
+// Feeds returns current feed rates.
+func (b *GCodeBuilder) Feeds() Feeds {
+	return b.feeds
+}
+
+// withFeed adds F to args if feed is set and differs from the last emitted one.
+func (b *GCodeBuilder) withFeed(args Args, feed float32) Args {
+	if feed > 0 && feed != b.feed {
+		args = append(args, Arg("F", RelativePos(feed)))
+		b.feed = feed
+	}
+
+	return args
+}
+
+// Tolerance returns current curve approximation tolerance.
+func (b *GCodeBuilder) Tolerance() float64 {
+	return b.tolerance
+}
+
 func (b *GCodeBuilder) PushCommand(c ...Command) *GCodeBuilder {
 	b.commands = append(b.commands, c...)
+
+	// keep track of the modal feed (commands may come from outside)
+	for _, cmd := range c {
+		if f, ok := cmd.Args.Get("F"); ok {
+			b.feed = float32(f)
+		}
+	}
+
 	return b
 }
 
@@ -126,10 +299,10 @@ func (b *GCodeBuilder) Up() error {
 
 	b.PushCommand(Command{
 		LineComment: "Stop drawing",
-		Code:        GCodeMove,
-		Args: Args{
-			"Z": RelativePos(b.depth),
-		},
+		Code:        b.dialect.MoveCode(false),
+		Args: b.withFeed(Args{
+			Arg("Z", b.moveZ(b.depth)),
+		}, b.feeds.Travel),
 	})
 
 	b.isDrawing = false
@@ -155,10 +328,10 @@ func (b *GCodeBuilder) Down() error {
 
 	b.PushCommand(Command{
 		LineComment: "Start drawing",
-		Code:        GCodeMove,
-		Args: Args{
-			"Z": RelativePos(-b.depth),
-		},
+		Code:        b.dialect.MoveCode(true),
+		Args: b.withFeed(Args{
+			Arg("Z", b.moveZ(-b.depth)),
+		}, b.feeds.Plunge),
 	})
 
 	b.isDrawing = true
@@ -166,6 +339,119 @@ func (b *GCodeBuilder) Down() error {
 	return nil
 }
 
+// moveZ updates current Z and returns Z argument for moving by delta.
+// Relative moves are rounded with compensation as in coords.
+func (b *GCodeBuilder) moveZ(delta RelativePos) RelativePos {
+	b.z += delta
+	if b.absolute {
+		return b.z
+	}
+
+	rel := b.round(HardwareAbsolutePos(b.z - b.machineZ))
+	b.machineZ += rel
+
+	return rel
+}
+
+// ShiftZ moves the head by delta along Z axis and makes the new height a reference
+// for the following commands, so that they can be repeated deeper (or calibrated).
+// In absolute mode this emits G92 after the move.
+func (b *GCodeBuilder) ShiftZ(delta RelativePos, comment string) *GCodeBuilder {
+	z := b.z
+	b.PushCommand(Command{
+		LineComment: comment,
+		Code:        b.dialect.MoveCode(true),
+		Args: b.withFeed(Args{
+			Arg("Z", b.moveZ(delta)),
+		}, b.feeds.Plunge),
+	})
+
+	b.z = z
+	b.machineZ -= delta
+	if b.absolute {
+		b.PushCommand(Command{
+			LineComment: "Set current height as reference",
+			Code:        GCodeSetPosition,
+			Args: Args{
+				Arg("Z", z),
+			},
+		})
+	}
+
+	return b
+}
+
+// BeginLoop starts a block of commands repeated n times (up to EndLoop).
+// Returns ErrUnsupportedFeature if the dialect does not support FeatureLoops.
+func (b *GCodeBuilder) BeginLoop(n int) error {
+	if !b.dialect.Supports(FeatureLoops) {
+		return fmt.Errorf("%s: loops: %w", b.dialect.Name(), ErrUnsupportedFeature)
+	}
+
+	b.loopID++
+	b.loops = append(b.loops, b.loopID)
+
+	begin, _ := b.dialect.Loop(b.loopID, n)
+	b.PushCommand(Command{
+		LineComment: fmt.Sprintf("Repeat %d times", n),
+		Code:        GCode(begin),
+	})
+
+	return nil
+}
+
+// EndLoop ends a block started with BeginLoop.
+func (b *GCodeBuilder) EndLoop() error {
+	if len(b.loops) == 0 {
+		return fmt.Errorf("called EndLoop but there is no loop: %w", ErrCantChangeDrawingState)
+	}
+
+	id := b.loops[len(b.loops)-1]
+	b.loops = b.loops[:len(b.loops)-1]
+
+	_, end := b.dialect.Loop(id, 0)
+	b.PushCommand(Command{
+		Code: GCode(end),
+	})
+
+	return nil
+}
+
+// coords returns X/Y arguments for moving from current position to p.
+// (relative offset or p itself in absolute mode)
+// Relative offsets are rounded as the dialect formats them and the rounding error
+// is compensated in the next move, so that it does not accumulate.
+func (b *GCodeBuilder) coords(p BetterPoint[HardwareAbsolutePos]) BetterPoint[RelativePos] {
+	if b.absolute {
+		return Redefine[RelativePos](p)
+	}
+
+	rel := BetterPt(b.round(p.X-b.machineP.X), b.round(p.Y-b.machineP.Y))
+	b.machineP = b.machineP.Add(Redefine[HardwareAbsolutePos](rel))
+
+	return rel
+}
+
+// machinePos returns where the machine is (in relative mode it differs from currentP
+// by the rounding error not compensated yet, see coords).
+func (b *GCodeBuilder) machinePos() BetterPoint[HardwareAbsolutePos] {
+	if b.absolute {
+		return b.currentP
+	}
+
+	return b.machineP
+}
+
+// round rounds v to the dialect's number format.
+func (b *GCodeBuilder) round(v HardwareAbsolutePos) RelativePos {
+	result, err := strconv.ParseFloat(b.outputDialect().FormatNumber(float64(v)), 32)
+	if err != nil {
+		return RelativePos(v)
+	}
+
+	return RelativePos(result)
+}
+
 // startDrawing moves to the starting point and calls Down
 func (b *GCodeBuilder) startDrawing(p BetterPoint[AbsolutePos]) error {
 	// 1.0: check if we are already drawing a continous line (if so check positions and return)
@@ -184,7 +470,10 @@ func (b *GCodeBuilder) startDrawing(p BetterPoint[AbsolutePos]) error {
 	}
 
 	// 1.1: go to x0, y0
-	b.Move(p)
+	if err := b.Move(p); err != nil {
+		return err
+	}
+
 	// 1.2: start drawing
 	if err := b.Down(); err != nil {
 		return err
@@ -220,59 +509,25 @@ func (b *GCodeBuilder) EndContinousLine() error {
 	return nil
 }
 
+// Base returns the base position - where the job starts and ends (see (*workspace.Workspace).Base).
+func (b *GCodeBuilder) Base() BetterPoint[AbsolutePos] {
+	x, y := b.workspace.Base()
+	return BetterPt(AbsolutePos(x-b.workspace.MinX), AbsolutePos(y-b.workspace.MinY))
+}
+
 // Current returns current position.
 func (b *GCodeBuilder) Current() BetterPoint[AbsolutePos] {
 	return Redefine[AbsolutePos](b.currentP.Add(BetterPt(HardwareAbsolutePos(-b.workspace.MinX), HardwareAbsolutePos(-b.workspace.MinY))))
 }
 
-// String returns built GCode.
+// String returns built GCode (see WriteTo).
 func (b *GCodeBuilder) String() string {
-	// actual build:
-	result := b.preamble
-	for _, c := range b.commands {
-		s := c.String(b.lineComments, b.commentsAbove)
-		if s == "" {
-			continue
-		}
-
-		result += s + "\n"
+	result := &strings.Builder{}
+	if _, err := b.WriteTo(result); err != nil {
+		glg.Errorf("Cannot build GCode: %v", err)
 	}
 
-	result += b.postamble
-
-	// now a bit tricky part.
-	// if comment is a linecomment, align it with other comments
-	// if not leave.
-	longest := 0
-	for _, line := range strings.Split(result, "\n") {
-		if strings.HasPrefix(line, " ;") || strings.HasPrefix(line, ";;") {
-			continue
-		}
-
-		l := strings.Split(line, ";")[0]
-		if len(l) > longest {
-			longest = len(l)
-		}
-	}
-
-	// align comments
-	lines := strings.Split(result, "\n")
-	for i, line := range lines {
-		if strings.HasPrefix(line, " ;") || strings.HasPrefix(line, ";;") {
-			continue
-		}
-
-		parts := strings.Split(line, ";")
-
-		if len(parts) == 1 {
-			continue // no comment
-		}
-
-		line = strings.Join(parts, fmt.Sprintf("%s;", string(slices.Repeat[[]byte]([]byte(" "), longest-len(parts[0])))))
-		lines[i] = line
-	}
-
-	return strings.Join(lines, "\n")
+	return result.String()
 }
 
 func (b *GCodeBuilder) RelToAbs(p BetterPoint[RelativePos]) BetterPoint[AbsolutePos] {
@@ -283,7 +538,7 @@ func (b *GCodeBuilder) RelToAbs(p BetterPoint[RelativePos]) BetterPoint[Absolute
 }
 
 func (b *GCodeBuilder) relToHwAbs(p BetterPoint[RelativePos]) BetterPoint[HardwareAbsolutePos] {
-	return b.validateHwAbs(Redefine[HardwareAbsolutePos](p).Add(b.currentP))
+	return Redefine[HardwareAbsolutePos](p).Add(b.currentP)
 }
 
 func (b *GCodeBuilder) absToRel(p BetterPoint[HardwareAbsolutePos]) BetterPoint[RelativePos] {
@@ -296,41 +551,8 @@ func (b *GCodeBuilder) Dump() {
 	fmt.Println(glg.Yellow(fmt.Sprintf("%#v", b)))
 }
 
-func validateAbs(p BetterPoint[AbsolutePos]) BetterPoint[AbsolutePos] {
-	switch {
-	case p.X < 0:
-		glg.Fatalf("Absolute position must be positive, got %f", p.X)
-	case p.Y < 0:
-		glg.Fatalf("Absolute position must be positive, got %f", p.Y)
-	}
-
-	return p
-}
-
-func (b *GCodeBuilder) validateHwAbs(p BetterPoint[HardwareAbsolutePos]) BetterPoint[HardwareAbsolutePos] {
-	switch {
-	case p.X < HardwareAbsolutePos(b.workspace.MinX):
-		_, file, line, ok := runtime.Caller(2)
-		glg.Infof("Called at: %s %d %v\n", file, line, ok)
-		glg.Fatalf("Absolute position must be larger than %d, got %f", b.workspace.MinX, p.X)
-	case p.X > HardwareAbsolutePos(b.workspace.MaxX):
-		_, file, line, ok := runtime.Caller(2)
-		glg.Infof("Called at: %s %d %v\n", file, line, ok)
-		glg.Fatalf("Absolute position must be less than %v, got %f", b.workspace.MaxX, p.X)
-	case p.Y < HardwareAbsolutePos(b.workspace.MinY):
-		_, file, line, ok := runtime.Caller(2)
-		glg.Infof("Called at: %s %d %v\n", file, line, ok)
-		glg.Fatalf("Absolute position must be larger than %d, got %f", b.workspace.MinY, p.Y)
-	case p.Y > HardwareAbsolutePos(b.workspace.MaxY):
-		_, file, line, ok := runtime.Caller(2)
-		glg.Infof("Called at: %s %d %v\n", file, line, ok)
-		glg.Fatalf("Absolute position must be less than %v, got %f", b.workspace.MaxY, p.Y)
-	}
-
-	return p
-}
-
 // translate converts AbsolutePos to HardwareAbsolutePos by adding b.workspace.MinX/Y
+// NOTE: translate does not check bounds (see validateHwAbs).
 func (b *GCodeBuilder) translate(p BetterPoint[AbsolutePos]) BetterPoint[HardwareAbsolutePos] {
-	return b.validateHwAbs(Redefine[HardwareAbsolutePos](p.Add(BetterPoint[AbsolutePos]{AbsolutePos(b.workspace.MinX), AbsolutePos(b.workspace.MinY)})))
+	return Redefine[HardwareAbsolutePos](p.Add(BetterPoint[AbsolutePos]{AbsolutePos(b.workspace.MinX), AbsolutePos(b.workspace.MinY)}))
 }
@@ -12,14 +12,21 @@ const (
 	G1 GCode = "G1"
 	// G2 is a clockwise arc move
 	G2 GCode = "G2"
+	// G3 is a counterclockwise arc move
+	G3 GCode = "G3"
 	// G5 is a cubic B-spline move
 	G5  GCode = "G5"
 	G90 GCode = "G90"
 	G91 GCode = "G91"
+	// G92 sets current position (without moving)
+	G92 GCode = "G92"
 
 	GCodeMove        = G0
+	GCodeDraw        = G1
 	GCodeArc         = G2
+	GCodeArcCCW      = G3
 	GCodeBezierCubic = G5
 	GCodeAbsolutePos = G90
 	GCodeRelativePos = G91
+	GCodeSetPosition = G92
 )
@@ -1,5 +1,7 @@
 package gcb
 
+import "github.com/gucio321/spiffy/pkg/geom"
+
 // BetterPoint is image.Point but better
 type BetterPoint[PointType ~float32] struct {
 	X, Y PointType
@@ -29,3 +31,20 @@ func RedefineSlice[T2, T1 ~float32](a []BetterPoint[T1]) []BetterPoint[T2] {
 
 	return result
 }
+
+func toGeom[T ~float32](p BetterPoint[T]) geom.Point {
+	return geom.Pt(float64(p.X), float64(p.Y))
+}
+
+func fromGeom(p geom.Point) BetterPoint[AbsolutePos] {
+	return BetterPt(AbsolutePos(p.X), AbsolutePos(p.Y))
+}
+
+func toGeomSlice[T ~float32](points []BetterPoint[T]) []geom.Point {
+	result := make([]geom.Point, len(points))
+	for i, p := range points {
+		result[i] = toGeom(p)
+	}
+
+	return result
+}
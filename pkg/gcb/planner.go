@@ -0,0 +1,169 @@
+package gcb
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gucio321/spiffy/pkg/gcode"
+)
+
+const (
+	// DefaultAcceleration is an acceleration (in mm/s²) assumed if G-code does not set it (see M204).
+	DefaultAcceleration = 1000
+	// JunctionDeviation (in mm) limits speed at corners as in Marlin's junction deviation.
+	JunctionDeviation = 0.013
+	// GCodeSetAcceleration sets acceleration (S for all, P for printing and T for travel moves).
+	GCodeSetAcceleration GCode = "M204"
+)
+
+// acceleration is machine's acceleration (in mm/s²) of drawing and travel moves.
+type acceleration struct {
+	draw, travel float64
+}
+
+// update changes acceleration if the block sets it (M204 or Klipper's SET_VELOCITY_LIMIT ACCEL=).
+func (a *acceleration) update(block gcode.Block) {
+	if block.Has(string(GCodeSetAcceleration)) {
+		// S sets both, so it goes first
+		for _, param := range []struct {
+			letter  byte
+			targets []*float64
+		}{
+			{'S', []*float64{&a.draw, &a.travel}},
+			{'P', []*float64{&a.draw}},
+			{'T', []*float64{&a.travel}},
+		} {
+			if w, ok := block.Param(param.letter); ok && w.Value > 0 {
+				for _, t := range param.targets {
+					*t = w.Value
+				}
+			}
+		}
+
+		return
+	}
+
+	if !strings.EqualFold(block.Extended, "SET_VELOCITY_LIMIT") {
+		return
+	}
+
+	for _, field := range strings.Fields(block.Text) {
+		key, value, _ := strings.Cut(field, "=")
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v > 0 && strings.EqualFold(key, "ACCEL") {
+			a.draw, a.travel = v, v
+		}
+	}
+}
+
+// move is a straight move planned by planner.
+type move struct {
+	length float64
+	// dir is a unit vector of the move
+	dir gcode.Point
+	// speed is the max speed (in mm/s) and accel is acceleration (in mm/s²)
+	speed, accel float64
+	// entry is the planned speed at the beginning of the move
+	entry float64
+	// pass is the index of job's pass (see BeginPass) or -1.
+	pass int
+}
+
+// planner estimates time of moves with trapezoidal speed profiles.
+// Speed at junctions is limited by the angle between moves (see JunctionDeviation)
+// and the machine stops at the end of the job.
+type planner struct {
+	moves []move
+}
+
+// add adds a polyline (e.g. flattened arc) driven at feed (in mm/min).
+func (p *planner) add(points []gcode.Point, feed, accel float64, pass int) {
+	for i := 1; i < len(points); i++ {
+		d := gcode.Point{X: points[i].X - points[i-1].X, Y: points[i].Y - points[i-1].Y, Z: points[i].Z - points[i-1].Z}
+		length := math.Sqrt(d.X*d.X + d.Y*d.Y + d.Z*d.Z)
+		if length == 0 {
+			continue
+		}
+
+		p.moves = append(p.moves, move{
+			length: length,
+			dir:    gcode.Point{X: d.X / length, Y: d.Y / length, Z: d.Z / length},
+			speed:  feed / 60,
+			accel:  accel,
+			pass:   pass,
+		})
+	}
+}
+
+// junctionSpeed returns the max speed between moves a and b.
+func junctionSpeed(a, b move) float64 {
+	limit := math.Min(a.speed, b.speed)
+
+	cos := -(a.dir.X*b.dir.X + a.dir.Y*b.dir.Y + a.dir.Z*b.dir.Z)
+	switch {
+	case cos > 0.999999: // reversal
+		return 0
+	case cos < -0.999999: // straight line
+		return limit
+	}
+
+	sinHalf := math.Sqrt(0.5 * (1 - cos))
+	accel := math.Min(a.accel, b.accel)
+
+	return math.Min(limit, math.Sqrt(accel*JunctionDeviation*sinHalf/(1-sinHalf)))
+}
+
+// plan returns time (in seconds) of every move.
+func (p *planner) plan() []float64 {
+	n := len(p.moves)
+
+	// 1.0: max entry speeds
+	for i := range p.moves {
+		p.moves[i].entry = 0
+		if i > 0 {
+			p.moves[i].entry = junctionSpeed(p.moves[i-1], p.moves[i])
+		}
+	}
+
+	// 1.1: backward pass - the machine must be able to slow down to the next entry speed (0 at the end)
+	exit := 0.0
+	for i := n - 1; i >= 0; i-- {
+		m := &p.moves[i]
+		m.entry = math.Min(m.entry, math.Sqrt(exit*exit+2*m.accel*m.length))
+		exit = m.entry
+	}
+
+	// 1.2: forward pass - the machine must be able to speed up to the next entry speed
+	for i := 0; i < n-1; i++ {
+		m := &p.moves[i]
+		next := &p.moves[i+1]
+		next.entry = math.Min(next.entry, math.Sqrt(m.entry*m.entry+2*m.accel*m.length))
+	}
+
+	// 1.3: trapezoids
+	result := make([]float64, n)
+	for i, m := range p.moves {
+		exit := 0.0
+		if i < n-1 {
+			exit = p.moves[i+1].entry
+		}
+
+		result[i] = trapezoidTime(m.length, m.entry, exit, m.speed, m.accel)
+	}
+
+	return result
+}
+
+// trapezoidTime returns time of a move of length which starts at speed v0, ends at v1
+// and accelerates up to vmax (or as fast as possible if the move is too short).
+func trapezoidTime(length, v0, v1, vmax, accel float64) float64 {
+	accelDist := (vmax*vmax - v0*v0) / (2 * accel)
+	decelDist := (vmax*vmax - v1*v1) / (2 * accel)
+	if accelDist+decelDist <= length {
+		return (vmax-v0)/accel + (vmax-v1)/accel + (length-accelDist-decelDist)/vmax
+	}
+
+	peak := math.Sqrt((2*accel*length + v0*v0 + v1*v1) / 2)
+
+	return (peak-v0)/accel + (peak-v1)/accel
+}
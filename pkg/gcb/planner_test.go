@@ -0,0 +1,159 @@
+package gcb
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gucio321/spiffy/pkg/gcode"
+	"github.com/gucio321/spiffy/pkg/workspace"
+)
+
+func TestTrapezoidTime(t *testing.T) {
+	tests := []struct {
+		name                        string
+		length, v0, v1, vmax, accel float64
+		want                        float64
+	}{
+		{"trapezoid", 100, 0, 0, 10, 10, 11},
+		{"triangle", 1, 0, 0, 10, 1, 2},
+		{"cruise only", 10, 5, 5, 5, 1, 2},
+		{"accelerate and cruise", 100, 0, 10, 10, 10, 10.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trapezoidTime(tt.length, tt.v0, tt.v1, tt.vmax, tt.accel); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanner_Plan(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []gcode.Point
+		want   float64
+	}{
+		{"single move", []gcode.Point{{}, {X: 100}}, 11},
+		{"straight line of two moves", []gcode.Point{{}, {X: 50}, {X: 100}}, 11},
+		{"reversal stops the machine", []gcode.Point{{}, {X: 50}, {}}, 12},
+		{"zero-length moves are skipped", []gcode.Point{{}, {}, {X: 100}}, 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &planner{}
+			p.add(tt.points, 600, 10, -1)
+
+			got := 0.0
+			for _, d := range p.plan() {
+				got += d
+			}
+
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJunctionSpeed(t *testing.T) {
+	a := move{dir: gcode.Point{X: 1}, speed: 10, accel: 1000}
+	tests := []struct {
+		name string
+		dir  gcode.Point
+		want func(float64) bool
+	}{
+		{"straight", gcode.Point{X: 1}, func(v float64) bool { return v == 10 }},
+		{"reversal", gcode.Point{X: -1}, func(v float64) bool { return v == 0 }},
+		{"right angle", gcode.Point{Y: 1}, func(v float64) bool { return v > 0 && v < 10 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := move{dir: tt.dir, speed: 10, accel: 1000}
+			if got := junctionSpeed(a, b); !tt.want(got) {
+				t.Errorf("unexpected junction speed %v", got)
+			}
+		})
+	}
+}
+
+func TestGCodeBuilder_Stats(t *testing.T) {
+	tests := []struct {
+		name    string
+		program string
+		draw    float64
+		travel  float64
+		lifts   int
+		passes  []float64
+	}{
+		{"draw and travel", "G1 X10\nG0 X0", 10, 10, 0, nil},
+		{"lift", "G1 Z-1\nG0 Z0", 1, 1, 1, nil},
+		{
+			"passes include moving down",
+			"; BEGIN PASS\nG1 X10\nG0 X0\n; BEGIN PASS\nG1 Z-1\nG1 X10",
+			21, 10, 0, []float64{10, 11},
+		},
+		{"single pass is not reported", "; BEGIN PASS\nG1 X10", 10, 0, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// program coordinates start at the base position
+			b, err := NewGCodeBuilderFromGCode([]byte("G92 X0 Y0 Z0\n" + tt.program))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			stats := b.Stats()
+			if math.Abs(stats.DrawLength-tt.draw) > 1e-9 || math.Abs(stats.TravelLength-tt.travel) > 1e-9 {
+				t.Errorf("draw and travel lengths are %v, %v, want %v, %v", stats.DrawLength, stats.TravelLength, tt.draw, tt.travel)
+			}
+
+			if stats.Lifts != tt.lifts {
+				t.Errorf("lifts: got %d, want %d", stats.Lifts, tt.lifts)
+			}
+
+			if len(stats.Passes) != len(tt.passes) {
+				t.Fatalf("got %d passes, want %d", len(stats.Passes), len(tt.passes))
+			}
+
+			for i, want := range tt.passes {
+				if got := stats.Passes[i].DrawLength; math.Abs(got-want) > 1e-9 {
+					t.Errorf("pass %d: draw length is %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGCodeBuilder_Stats_Preamble(t *testing.T) {
+	ws, err := workspace.Get(DefaultWorkspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drawing (without F) is at F1000 of GRBL's preamble, not at DefaultFeed
+	grbl := NewGCodeBuilder(ws).SetDialect(GRBL)
+	if err := grbl.DrawLine(BetterPoint[AbsolutePos]{0, 0}, BetterPoint[AbsolutePos]{100, 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := grbl.Stats()
+	if want := time.Duration(stats.DrawLength / 1000 * float64(time.Minute)); stats.Time < want {
+		t.Errorf("estimated time is %v, want at least %v", stats.Time, want)
+	}
+
+	// 100mm at 100mm/s and 1000mm/s² of a file without M204 (not 2000mm/s² of Marlin's preamble)
+	file, err := NewGCodeBuilderFromGCode([]byte("G92 X0 Y0 Z0\nG1 X100 F6000\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := file.Stats().Time, 1100*time.Millisecond; (got - want).Abs() > time.Millisecond {
+		t.Errorf("estimated time of the file is %v, want %v", got, want)
+	}
+}
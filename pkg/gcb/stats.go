@@ -0,0 +1,179 @@
+package gcb
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gucio321/spiffy/pkg/gcode"
+	"github.com/gucio321/spiffy/pkg/geom"
+	"github.com/kpango/glg"
+)
+
+// DefaultFeed is a feed (in mm/min) assumed if no F was set (see DefaultPreamble).
+const DefaultFeed = 5000
+
+// PassMarker is a comment beginning every pass of a repeated job (see BeginPass).
+const PassMarker = "BEGIN PASS"
+
+// Stats are statistics of the job.
+type Stats struct {
+	// DrawLength and TravelLength are lengths (in mm) of drawing and travel moves (including Z).
+	DrawLength, TravelLength float64
+	// Lifts is a number of tool lifts (moves up along Z axis only).
+	Lifts int
+	// MinZ and MaxZ is a range of Z positions in machine coordinates.
+	MinZ, MaxZ float64
+	// BBox is a bounding box of drawing moves in machine coordinates (HardwareAbsolutePos).
+	BBox geom.Rect
+	// Time is an estimated time of the job (see planner).
+	Time time.Duration
+	// Passes are statistics of passes of a repeated job (see BeginPass). Empty if there is only one pass.
+	Passes []Stats
+}
+
+func newStats() Stats {
+	return Stats{
+		BBox: geom.EmptyRect(),
+		MinZ: math.Inf(1),
+		MaxZ: math.Inf(-1),
+	}
+}
+
+// add adds the segment flattened to points.
+func (s *Stats) add(segment gcode.Segment, points []gcode.Point) {
+	length := segment.Length()
+	if segment.Rapid {
+		s.TravelLength += length
+	} else {
+		s.DrawLength += length
+		for _, p := range points {
+			s.BBox = s.BBox.Extend(geom.Pt(p.X, p.Y))
+		}
+	}
+
+	if segment.End.Z > segment.Start.Z && segment.End.X == segment.Start.X && segment.End.Y == segment.Start.Y {
+		s.Lifts++
+	}
+
+	s.MinZ = math.Min(s.MinZ, math.Min(segment.Start.Z, segment.End.Z))
+	s.MaxZ = math.Max(s.MaxZ, math.Max(segment.Start.Z, segment.End.Z))
+}
+
+// finish fixes Z range of empty stats.
+func (s *Stats) finish() {
+	if s.MinZ > s.MaxZ {
+		s.MinZ, s.MaxZ = 0, 0
+	}
+}
+
+// Machine returns a machine (see gcode.Machine) in the state of the builder before its commands:
+// at the base position (in machine coordinates) and in builder's positioning mode.
+func (b *GCodeBuilder) Machine() *gcode.Machine {
+	return gcode.NewMachine(b.machineState())
+}
+
+func (b *GCodeBuilder) machineState() gcode.State {
+	base := b.translate(b.Base())
+	state := gcode.NewState()
+	state.Absolute = b.absolute
+	state.Position = gcode.Point{X: float64(base.X), Y: float64(base.Y)}
+
+	return state
+}
+
+// BeginPass marks beginning of a pass of a repeated job (see Stats).
+// Call it before going down to the pass (e.g. ShiftZ), so that the move is a part of the pass.
+func (b *GCodeBuilder) BeginPass() *GCodeBuilder {
+	return b.Comment(PassMarker)
+}
+
+// Stats executes the commands (see Machine) and calculates job's statistics.
+// Repeat loops are expanded (see gcode.Unroll). Feed and acceleration are taken from
+// the preamble (F and M204 or SET_VELOCITY_LIMIT) and the commands.
+// Moves of the preamble are not counted.
+func (b *GCodeBuilder) Stats() Stats {
+	// 1.0: feed and acceleration set in the preamble (rendered without stats, which would need the preamble)
+	state := b.machineState()
+	accel := acceleration{DefaultAcceleration, DefaultAcceleration}
+	if preamble, err := b.render("preamble", b.preambleTemplate(), b.templateData(Stats{})); err == nil {
+		if blocks, err := gcode.Parse([]byte(preamble)); err == nil {
+			machine := gcode.NewMachine(state)
+			for _, block := range blocks {
+				accel.update(block)
+				// invalid moves don't change the feed
+				_, _ = machine.Execute(&block)
+			}
+
+			state.Feed = machine.State().Feed
+		}
+	}
+
+	// 1.1: commands
+	blocks := make([]gcode.Block, len(b.commands))
+	for i, cmd := range b.commands {
+		blocks[i] = cmd.Block()
+		blocks[i].Line = i + 1
+	}
+
+	if unrolled, err := gcode.Unroll(blocks); err == nil {
+		blocks = unrolled
+	} else {
+		glg.Warnf("Stats: loops are not expanded: %v", err)
+	}
+
+	// 1.2: execute
+	result := newStats()
+	passes := []Stats{}
+	plan := &planner{}
+	machine := gcode.NewMachine(state)
+	for _, block := range blocks {
+		if len(block.Comments) > 0 && strings.HasPrefix(block.Comments[0], PassMarker) {
+			passes = append(passes, newStats())
+		}
+
+		accel.update(block)
+
+		segment, err := machine.Execute(&block)
+		if err != nil || segment == nil {
+			continue
+		}
+
+		points := segment.Flatten(b.tolerance)
+		result.add(*segment, points)
+		if len(passes) > 0 {
+			passes[len(passes)-1].add(*segment, points)
+		}
+
+		feed, a := segment.Feed, accel.draw
+		if feed <= 0 {
+			feed = DefaultFeed
+		}
+
+		if segment.Rapid {
+			a = accel.travel
+		}
+
+		plan.add(points, feed, a, len(passes)-1)
+	}
+
+	// 1.3: time
+	for i, t := range plan.plan() {
+		d := time.Duration(t * float64(time.Second))
+		result.Time += d
+		if pass := plan.moves[i].pass; pass >= 0 {
+			passes[pass].Time += d
+		}
+	}
+
+	result.finish()
+	if len(passes) > 1 {
+		for i := range passes {
+			passes[i].finish()
+		}
+
+		result.Passes = passes
+	}
+
+	return result
+}
@@ -0,0 +1,240 @@
+package gcb
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+	"github.com/gucio321/spiffy/pkg/workspace"
+)
+
+// TemplateData is passed to preamble and postamble templates (see SetPreamble).
+// Example: G0 X{{.Base.X}} Y{{.Base.Y}}
+type TemplateData struct {
+	// Workspace is the builder's workspace (bounds are in .Workspace.MinX etc.)
+	Workspace *workspace.Workspace
+	// Base is the base position in machine coordinates.
+	Base BetterPoint[HardwareAbsolutePos]
+	// Feeds are builder's feeds (see SetFeeds).
+	Feeds Feeds
+	// JobName is a name of the job (see SetJobName).
+	JobName string
+	// Dialect is a name of the dialect.
+	Dialect string
+	// Absolute is true in absolute positioning mode.
+	Absolute bool
+	// BBox is a bounding box of the drawing in machine coordinates.
+	BBox geom.Rect
+	// EstimatedTime is an estimated time of the job (see Stats).
+	EstimatedTime time.Duration
+}
+
+// statsFields are fields of TemplateData which need Stats.
+var statsFields = map[string]bool{"BBox": true, "EstimatedTime": true}
+
+// templateFuncs are functions available in templates.
+// num formats a number as the dialect does.
+func (b *GCodeBuilder) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"num": func(v any) (string, error) {
+			d := b.outputDialect()
+			switch v := v.(type) {
+			case float64:
+				return d.FormatNumber(v), nil
+			case float32:
+				return d.FormatNumber(float64(v)), nil
+			case HardwareAbsolutePos:
+				return d.FormatNumber(float64(v)), nil
+			case int:
+				return d.FormatNumber(float64(v)), nil
+			default:
+				return "", fmt.Errorf("num: unexpected type %T", v)
+			}
+		},
+	}
+}
+
+func (b *GCodeBuilder) parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(b.templateFuncs()).Parse(text)
+}
+
+// SetPreamble sets text/template code executed before the job (see TemplateData).
+// Empty text resets to the dialect's default (none for builders read from a file).
+func (b *GCodeBuilder) SetPreamble(text string) error {
+	if _, err := b.parseTemplate("preamble", text); err != nil {
+		return fmt.Errorf("invalid preamble template: %w", err)
+	}
+
+	b.preamble = text
+
+	return nil
+}
+
+// SetPostamble sets text/template code executed after the job (see TemplateData).
+// Empty text resets to the dialect's default (none for builders read from a file).
+func (b *GCodeBuilder) SetPostamble(text string) error {
+	if _, err := b.parseTemplate("postamble", text); err != nil {
+		return fmt.Errorf("invalid postamble template: %w", err)
+	}
+
+	b.postamble = text
+
+	return nil
+}
+
+// SetJobName sets job name available in templates.
+func (b *GCodeBuilder) SetJobName(name string) *GCodeBuilder {
+	b.jobName = name
+	return b
+}
+
+// TemplateData returns data passed to the templates.
+// It executes all the commands to calculate the statistics (see Stats).
+func (b *GCodeBuilder) TemplateData() TemplateData {
+	return b.templateData(b.Stats())
+}
+
+// templateDataFor returns data passed to the templates.
+// Stats are calculated only if any of the templates uses them (see usesStats).
+func (b *GCodeBuilder) templateDataFor(templates ...string) TemplateData {
+	for _, text := range templates {
+		if b.usesStats(text) {
+			return b.TemplateData()
+		}
+	}
+
+	return b.templateData(Stats{})
+}
+
+// usesStats returns true if the template uses fields which need Stats (see statsFields).
+// Invalid templates are reported as using them, so that render reports the error.
+func (b *GCodeBuilder) usesStats(text string) bool {
+	t, err := b.parseTemplate("", text)
+	if err != nil {
+		return true
+	}
+
+	for _, t := range t.Templates() {
+		if t.Tree != nil && usesStats(t.Tree.Root) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func usesStats(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		return statsFields[n.Ident[0]]
+	case *parse.VariableNode:
+		// $.EstimatedTime
+		return len(n.Ident) > 1 && n.Ident[0] == "$" && statsFields[n.Ident[1]]
+	case *parse.ChainNode:
+		return usesStats(n.Node)
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+
+		for _, child := range n.Nodes {
+			if usesStats(child) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return usesStats(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+
+		for _, cmd := range n.Cmds {
+			if usesStats(cmd) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if usesStats(arg) {
+				return true
+			}
+		}
+	case *parse.IfNode:
+		return usesStats(&n.BranchNode)
+	case *parse.RangeNode:
+		return usesStats(&n.BranchNode)
+	case *parse.WithNode:
+		return usesStats(&n.BranchNode)
+	case *parse.BranchNode:
+		return usesStats(n.Pipe) || usesStats(n.List) || usesStats(n.ElseList)
+	case *parse.TemplateNode:
+		return usesStats(n.Pipe)
+	}
+
+	return false
+}
+
+func (b *GCodeBuilder) templateData(stats Stats) TemplateData {
+	return TemplateData{
+		Workspace:     b.workspace,
+		Base:          b.translate(b.Base()),
+		Feeds:         b.feeds,
+		JobName:       b.jobName,
+		Dialect:       b.dialect.Name(),
+		Absolute:      b.absolute,
+		BBox:          stats.BBox,
+		EstimatedTime: stats.Time.Round(time.Second),
+	}
+}
+
+// Preamble renders the preamble.
+func (b *GCodeBuilder) Preamble() (string, error) {
+	return b.render("preamble", b.preambleTemplate(), b.templateDataFor(b.preambleTemplate()))
+}
+
+// preambleTemplate returns preamble's template (see SetPreamble).
+func (b *GCodeBuilder) preambleTemplate() string {
+	switch {
+	case b.preamble != "":
+		return b.preamble
+	case b.wholeFile:
+		return ""
+	}
+
+	return b.dialect.Preamble(b.absolute)
+}
+
+// Postamble renders the postamble.
+func (b *GCodeBuilder) Postamble() (string, error) {
+	return b.render("postamble", b.postambleTemplate(), b.templateDataFor(b.postambleTemplate()))
+}
+
+// postambleTemplate returns postamble's template (see SetPostamble).
+func (b *GCodeBuilder) postambleTemplate() string {
+	switch {
+	case b.postamble != "":
+		return b.postamble
+	case b.wholeFile:
+		return ""
+	}
+
+	return b.dialect.Postamble()
+}
+
+func (b *GCodeBuilder) render(name, text string, data TemplateData) (string, error) {
+	t, err := b.parseTemplate(name, text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+
+	result := &strings.Builder{}
+	if err := t.Execute(result, data); err != nil {
+		return "", fmt.Errorf("cant execute %s template: %w", name, err)
+	}
+
+	return result.String(), nil
+}
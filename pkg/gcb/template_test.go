@@ -0,0 +1,40 @@
+package gcb
+
+import (
+	"testing"
+
+	"github.com/gucio321/spiffy/pkg/workspace"
+)
+
+func TestGCodeBuilder_usesStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     bool
+	}{
+		{"no fields", "G90\n", false},
+		{"other fields", "G0 X{{.Base.X}} Y{{.Base.Y}} F{{if .Feeds.Travel}}{{.Feeds.Travel}}{{end}}", false},
+		{"estimated time", "; {{.EstimatedTime}}", true},
+		{"bbox in a function", "G0 X{{num .BBox.Min.X}}", true},
+		{"in a condition", "{{if .JobName}}{{else}}{{.EstimatedTime}}{{end}}", true},
+		{"in with", "{{with .BBox}}{{.Min.X}}{{end}}", true},
+		{"root variable", "{{with .Workspace}}{{.Name}} {{$.EstimatedTime}}{{end}}", true},
+		{"in a defined template", `{{define "t"}}{{.BBox}}{{end}}{{template "t" .}}`, true},
+		{"default preamble", DefaultPreamble, true},
+		{"invalid", "{{.EstimatedTime", true},
+	}
+
+	ws, err := workspace.Get(DefaultWorkspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewGCodeBuilder(ws)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.usesStats(tt.template); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package gcb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// AlignToLongest aligns line comments with the longest line (see AlignComments).
+	// Output is formatted twice: first to find the longest line and then to write it.
+	AlignToLongest = 0
+	// NoAlignment leaves line comments where they are.
+	NoAlignment = -1
+)
+
+// WriteTo writes built GCode (preamble, commands and postamble) to w.
+// Commands are formatted line by line, so the output is never kept in memory.
+// NOTE: if the preamble or the postamble uses statistics (e.g. .EstimatedTime, as the default ones do),
+// all the commands are executed first (see Stats).
+func (b *GCodeBuilder) WriteTo(w io.Writer) (int64, error) {
+	data := b.templateDataFor(b.preambleTemplate(), b.postambleTemplate())
+
+	preamble, err := b.render("preamble", b.preambleTemplate(), data)
+	if err != nil {
+		return 0, err
+	}
+
+	postamble, err := b.render("postamble", b.postambleTemplate(), data)
+	if err != nil {
+		return 0, err
+	}
+
+	lw := &lineWriter{
+		w:      bufio.NewWriter(w),
+		column: b.commentColumn,
+	}
+
+	// 1.0: find the longest line
+	if lw.column == AlignToLongest {
+		lw.measure = true
+		if err := b.writeLines(lw, preamble, postamble); err != nil {
+			return 0, err
+		}
+
+		lw.column, lw.measure = lw.longest, false
+	}
+
+	// 1.1: write
+	if err := b.writeLines(lw, preamble, postamble); err != nil {
+		return lw.n, err
+	}
+
+	return lw.n, lw.w.Flush()
+}
+
+func (b *GCodeBuilder) writeLines(lw *lineWriter, preamble, postamble string) error {
+	d := b.outputDialect()
+
+	if err := lw.WriteString(preamble); err != nil {
+		return err
+	}
+
+	for _, c := range b.commands {
+		s := c.Format(d, b.lineComments, b.commentsAbove)
+		if s == "" {
+			continue
+		}
+
+		if err := lw.WriteString(s + "\n"); err != nil {
+			return err
+		}
+	}
+
+	if err := lw.WriteString(postamble); err != nil {
+		return err
+	}
+
+	return lw.flushLine(false)
+}
+
+// lineWriter splits text into lines and aligns their comments to column.
+type lineWriter struct {
+	w       *bufio.Writer
+	n       int64
+	column  int
+	pending []byte
+	// measure makes lineWriter only find the longest line (without comment).
+	measure bool
+	longest int
+}
+
+func (l *lineWriter) WriteString(s string) error {
+	for {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			l.pending = append(l.pending, s...)
+			return nil
+		}
+
+		l.pending = append(l.pending, s[:i]...)
+		if err := l.flushLine(true); err != nil {
+			return err
+		}
+
+		s = s[i+1:]
+	}
+}
+
+// flushLine writes the pending line.
+// Comment-only lines are left as they are.
+func (l *lineWriter) flushLine(newline bool) error {
+	line := string(l.pending)
+	l.pending = l.pending[:0]
+
+	code, comment, hasComment := strings.Cut(line, ";")
+	isComment := strings.TrimSpace(code) == "" && hasComment
+
+	if l.measure {
+		if !isComment {
+			l.longest = max(l.longest, len(code))
+		}
+
+		return nil
+	}
+
+	if hasComment && !isComment && l.column > len(code) {
+		line = code + strings.Repeat(" ", l.column-len(code)) + ";" + comment
+	}
+
+	if newline {
+		line += "\n"
+	}
+
+	n, err := l.w.WriteString(line)
+	l.n += int64(n)
+
+	if err != nil {
+		return fmt.Errorf("cant write GCode: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package gcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrSyntax = errors.New("invalid G-code")
+	// ErrChecksum is returned if line's checksum (*nn) does not match its content.
+	ErrChecksum = errors.New("checksum mismatch")
+	// ErrInvalidArc is returned for G2/G3 without I, J nor R or with a radius shorter than half of the chord.
+	ErrInvalidArc = errors.New("invalid arc")
+	// ErrInvalidBezier is returned for G5 without control points.
+	ErrInvalidBezier = errors.New("invalid bezier")
+)
+
+// SyntaxError describes where the parser failed.
+type SyntaxError struct {
+	// Line and Column are 1-based position of the error.
+	Line, Column int
+	Msg          string
+	// Err is ErrSyntax or ErrChecksum.
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,290 @@
+package gcode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// textCodes are commands taking the rest of the line as a text (e.g. M117 Hello).
+var textCodes = map[string]bool{
+	"M23": true, "M28": true, "M30": true, "M32": true, "M117": true, "M118": true, "M928": true,
+}
+
+// lexer tokenizes a single line of G-code.
+//
+// Words may be written without separators ("G0X10Y5") and in lower case.
+// Comments are either parenthesised ("(comment)") or start with a semicolon.
+// Line may start with a block delete ("/") and a line number ("N10") and end with a checksum ("*57").
+type lexer struct {
+	data  string
+	pos   int
+	line  int
+	block Block
+}
+
+func newLexer(data string, line int) *lexer {
+	data = strings.TrimRight(data, "\r")
+
+	return &lexer{
+		data:  data,
+		line:  line,
+		block: Block{Line: line, Raw: data},
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func toUpper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+
+	return c
+}
+
+func (l *lexer) skipSpaces() {
+	for l.pos < len(l.data) && isSpace(l.data[l.pos]) {
+		l.pos++
+	}
+}
+
+func (l *lexer) errorf(pos int, format string, args ...any) error {
+	return &SyntaxError{Line: l.line, Column: pos + 1, Msg: fmt.Sprintf(format, args...), Err: ErrSyntax}
+}
+
+// lex reads the whole line.
+func (l *lexer) lex() (Block, error) {
+	// 1.0: program delimiter (LinuxCNC)
+	if strings.TrimSpace(l.data) == "%" {
+		return l.block, nil
+	}
+
+	// 1.1: block delete
+	l.skipSpaces()
+	if l.pos < len(l.data) && l.data[l.pos] == '/' {
+		l.block.Deleted = true
+		l.pos++
+	}
+
+	// 1.2: extended commands (e.g. SET_VELOCITY_LIMIT ACCEL=2000 or o100 repeat [3])
+	l.skipSpaces()
+	if l.isExtended() {
+		return l.block, l.extended()
+	}
+
+	// 2.0: words and comments
+	for {
+		l.skipSpaces()
+		if l.pos >= len(l.data) {
+			return l.block, nil
+		}
+
+		c := l.data[l.pos]
+		switch {
+		case c == ';':
+			l.lineComment()
+		case c == '(':
+			if err := l.comment(); err != nil {
+				return l.block, err
+			}
+		case c == '*':
+			if err := l.checksum(); err != nil {
+				return l.block, err
+			}
+		case isLetter(c):
+			if err := l.word(); err != nil {
+				return l.block, err
+			}
+		default:
+			return l.block, l.errorf(l.pos, "unexpected character %q", c)
+		}
+	}
+}
+
+// isExtended returns true if the line starts with a non-standard command:
+// a name (e.g. SET_VELOCITY_LIMIT) or an o-word.
+func (l *lexer) isExtended() bool {
+	if l.pos+1 >= len(l.data) || !isLetter(l.data[l.pos]) {
+		return false
+	}
+
+	next := l.data[l.pos+1]
+	if toUpper(l.data[l.pos]) == 'O' {
+		return isDigit(next) || next == '<'
+	}
+
+	return isLetter(next) || next == '_'
+}
+
+// extended reads a non-standard command and its arguments as a text.
+func (l *lexer) extended() error {
+	start := l.pos
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && l.data[l.pos] != ';' {
+		l.pos++
+	}
+
+	l.block.Extended = l.data[start:l.pos]
+
+	return l.text()
+}
+
+// text reads the rest of the line (up to a semicolon comment or a checksum) as block's text.
+func (l *lexer) text() error {
+	end := len(l.data)
+	if i := strings.IndexByte(l.data[l.pos:], ';'); i >= 0 {
+		end = l.pos + i
+	}
+
+	// checksum is a '*' followed only by digits
+	if i := strings.LastIndexByte(l.data[l.pos:end], '*'); i >= 0 {
+		digits := strings.TrimSpace(l.data[l.pos+i+1 : end])
+		if _, err := strconv.Atoi(digits); err == nil {
+			end = l.pos + i
+		}
+	}
+
+	l.block.Text = strings.TrimSpace(l.data[l.pos:end])
+	l.pos = end
+
+	for {
+		l.skipSpaces()
+		if l.pos >= len(l.data) {
+			return nil
+		}
+
+		switch c := l.data[l.pos]; c {
+		case '*':
+			if err := l.checksum(); err != nil {
+				return err
+			}
+		case ';':
+			l.lineComment()
+		default:
+			return l.errorf(l.pos, "unexpected character %q", c)
+		}
+	}
+}
+
+func (l *lexer) lineComment() {
+	l.block.Comments = append(l.block.Comments, strings.TrimSpace(l.data[l.pos+1:]))
+	l.pos = len(l.data)
+}
+
+func (l *lexer) comment() error {
+	end := strings.IndexByte(l.data[l.pos:], ')')
+	if end < 0 {
+		return l.errorf(l.pos, "unclosed comment")
+	}
+
+	l.block.Comments = append(l.block.Comments, strings.TrimSpace(l.data[l.pos+1:l.pos+end]))
+	l.pos += end + 1
+
+	return nil
+}
+
+// checksum verifies Marlin-style checksum: XOR of all bytes before '*'.
+func (l *lexer) checksum() error {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.data) && isDigit(l.data[l.pos]) {
+		l.pos++
+	}
+
+	expected, err := strconv.Atoi(l.data[start+1 : l.pos])
+	if err != nil {
+		return l.errorf(start, "invalid checksum")
+	}
+
+	sum := 0
+	for i := 0; i < start; i++ {
+		sum ^= int(l.data[i])
+	}
+
+	if sum != expected {
+		return &SyntaxError{
+			Line:   l.line,
+			Column: start + 1,
+			Msg:    fmt.Sprintf("checksum is %d, expected %d", sum, expected),
+			Err:    ErrChecksum,
+		}
+	}
+
+	return nil
+}
+
+// word reads a letter and its value (if any).
+func (l *lexer) word() error {
+	start := l.pos
+	w := Word{Letter: toUpper(l.data[l.pos]), Column: start + 1}
+	l.pos++
+
+	// 1.0: value: sign, digits and at most one dot
+	valueStart := l.pos
+	if l.pos < len(l.data) && (l.data[l.pos] == '-' || l.data[l.pos] == '+') {
+		l.pos++
+	}
+
+	digits := 0
+	dot := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '.' && !dot {
+			dot = true
+		} else if isDigit(c) {
+			digits++
+		} else {
+			break
+		}
+
+		l.pos++
+	}
+
+	if l.pos > valueStart && digits == 0 {
+		return l.errorf(valueStart, "expected number after %c", w.Letter)
+	}
+
+	// 1.1: flag (no value)
+	if digits == 0 {
+		l.addWord(w)
+		return nil
+	}
+
+	w.Raw = l.data[valueStart:l.pos]
+
+	value, err := strconv.ParseFloat(w.Raw, 64)
+	if err != nil {
+		return l.errorf(valueStart, "invalid number %q", w.Raw)
+	}
+
+	w.Value = value
+	l.addWord(w)
+
+	// 1.2: text commands take the rest of the line
+	if w.IsCode() && textCodes[w.Code()] {
+		return l.text()
+	}
+
+	return nil
+}
+
+func (l *lexer) addWord(w Word) {
+	if w.Letter == 'N' && len(l.block.Words) == 0 && !l.block.HasNumber && w.IsInt() {
+		l.block.Number = int(w.Value)
+		l.block.HasNumber = true
+
+		return
+	}
+
+	l.block.Words = append(l.block.Words, w)
+}
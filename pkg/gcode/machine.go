@@ -0,0 +1,305 @@
+package gcode
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+// lengthTolerance is a tolerance (in mm) of bezier flattening used by (Segment).Length.
+const lengthTolerance = 0.001
+
+// SegmentKind is a kind of the machine's move.
+type SegmentKind int
+
+const (
+	// SegmentLine is a linear move (G0, G1).
+	SegmentLine SegmentKind = iota
+	// SegmentArc is a circular (or helical) move in XY plane (G2, G3).
+	SegmentArc
+	// SegmentBezier is a cubic bezier move in XY plane (G5).
+	SegmentBezier
+)
+
+// Segment is a single move of the machine. All points are machine positions in mm.
+type Segment struct {
+	Kind SegmentKind
+	// Rapid is true for travel moves (G0).
+	Rapid      bool
+	Start, End Point
+	// Center and Clockwise describe arcs (Z of Center is not used).
+	Center    Point
+	Clockwise bool
+	// Control1 and Control2 are bezier's control points (Z is not used).
+	Control1, Control2 Point
+	// Feed is a feed rate in mm/min (0 if not set).
+	Feed float64
+	// Line is the line of the block the segment comes from.
+	Line int
+}
+
+func (p Point) xy() geom.Point {
+	return geom.Pt(p.X, p.Y)
+}
+
+// path returns the segment's shape in XY plane (approximated with lines).
+func (s Segment) path(tolerance float64) []geom.Point {
+	switch s.Kind {
+	case SegmentArc:
+		return geom.CircularArc(s.Center.xy(), s.Start.xy(), s.End.xy(), s.Clockwise).Flatten(tolerance)
+	case SegmentBezier:
+		return geom.FlattenBezier(tolerance, s.Start.xy(), s.Control1.xy(), s.Control2.xy(), s.End.xy())
+	default:
+		return []geom.Point{s.Start.xy(), s.End.xy()}
+	}
+}
+
+// Flatten approximates the segment with a polyline (see geom.Arc.Flatten).
+// Z changes linearly along the path. Result contains both start and end point.
+func (s Segment) Flatten(tolerance float64) []Point {
+	path := s.path(tolerance)
+
+	// 1.0: distance along the path
+	distances := make([]float64, len(path))
+	for i := 1; i < len(path); i++ {
+		distances[i] = distances[i-1] + path[i].Dist(path[i-1])
+	}
+
+	total := distances[len(distances)-1]
+
+	// 1.1: interpolate Z
+	result := make([]Point, len(path))
+	for i, p := range path {
+		t := 1.0
+		if total > 0 {
+			t = distances[i] / total
+		}
+
+		result[i] = Point{p.X, p.Y, s.Start.Z + (s.End.Z-s.Start.Z)*t}
+	}
+
+	result[0], result[len(result)-1] = s.Start, s.End
+
+	return result
+}
+
+// Length returns length of the move (in mm, including Z).
+func (s Segment) Length() float64 {
+	xy := 0.0
+	switch s.Kind {
+	case SegmentArc:
+		arc := geom.CircularArc(s.Center.xy(), s.Start.xy(), s.End.xy(), s.Clockwise)
+		xy = math.Abs(arc.Delta) * arc.RX
+	default:
+		path := s.path(lengthTolerance)
+		for i := 1; i < len(path); i++ {
+			xy += path[i].Dist(path[i-1])
+		}
+	}
+
+	return math.Hypot(xy, s.End.Z-s.Start.Z)
+}
+
+// Machine executes G-code blocks and tracks the machine state.
+// Arcs and beziers are supported in XY plane (G17) only.
+type Machine struct {
+	state State
+	// control2 is the second control point of the last G5 move (used by G5 without I and J).
+	control2 *Point
+}
+
+// NewMachine creates a machine in the given state (see NewState).
+func NewMachine(state State) *Machine {
+	return &Machine{state: state}
+}
+
+// State returns the current state.
+func (m *Machine) State() State {
+	return m.state
+}
+
+// Run executes all blocks and returns their moves.
+// Loops are not expanded (see Unroll).
+func (m *Machine) Run(blocks []Block) ([]Segment, error) {
+	result := []Segment{}
+	for i := range blocks {
+		segment, err := m.Execute(&blocks[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if segment != nil {
+			result = append(result, *segment)
+		}
+	}
+
+	return result, nil
+}
+
+// Execute executes the block: sets its Motion and State and returns its move (nil if the block does not move).
+// Modal codes (units, positioning) are applied before position changes.
+// Axis words without a motion code move in the modal motion mode
+// only if the block has no codes other than modal ones (e.g. G91, see modalCodes),
+// so parameters of G92, M92 or M203 never move.
+func (m *Machine) Execute(block *Block) (*Segment, error) {
+	s := &m.state
+	var result *Segment
+
+	// 1.0: modes
+	for _, w := range block.Codes() {
+		switch code := w.Code(); code {
+		case "G20", "G21":
+			s.Inches = code == "G20"
+		case "G90", "G91":
+			s.Absolute = code == "G90"
+		case "G80":
+			s.Motion = ""
+		default:
+			if motionCodes[code] {
+				s.Motion = code
+				block.Motion = code
+			}
+		}
+	}
+
+	if f, ok := block.Param('F'); ok && !f.IsFlag() {
+		s.Feed = f.Value
+	}
+
+	// 1.1: position
+	switch {
+	case block.Has("G92"):
+		for _, w := range block.Params() {
+			if v := s.Offset.axis(w.Letter); v != nil && !w.IsFlag() {
+				*v = *s.Position.axis(w.Letter) - w.Value*s.Unit()
+			}
+		}
+	case block.Has("G92.1"):
+		s.Offset = Point{}
+	case block.Has("G28"):
+		home := Point{}
+		flags := false
+		for _, w := range block.Params() {
+			if v := s.Position.axis(w.Letter); v != nil {
+				*v = *home.axis(w.Letter)
+				flags = true
+			}
+		}
+
+		if !flags {
+			s.Position = home
+		}
+	case block.Motion != "" || (s.Motion != "" && block.hasAxes() && block.onlyModalCodes()):
+		block.Motion = s.Motion
+
+		var err error
+		if result, err = m.move(block); err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", block.Line, block.Motion, err)
+		}
+	}
+
+	if block.Motion != "G5" {
+		m.control2 = nil
+	}
+
+	block.State = *s
+
+	return result, nil
+}
+
+// move executes a motion block.
+func (m *Machine) move(block *Block) (*Segment, error) {
+	s := &m.state
+	_, hasI := block.Param('I')
+	_, hasJ := block.Param('J')
+	_, hasR := block.Param('R')
+	isArc := block.Motion == "G2" || block.Motion == "G3"
+
+	if !block.hasAxes() && !(isArc && (hasI || hasJ)) {
+		return nil, nil
+	}
+
+	result := &Segment{
+		Start: s.Position,
+		End:   s.Target(block.Params()),
+		Feed:  s.Feed * s.Unit(),
+		Line:  block.Line,
+	}
+
+	// offset returns value of the word (in mm) or 0
+	offset := func(letter byte) float64 {
+		w, _ := block.Param(letter)
+		return w.Value * s.Unit()
+	}
+
+	switch block.Motion {
+	case "G0":
+		result.Rapid = true
+	case "G2", "G3":
+		result.Kind = SegmentArc
+		result.Clockwise = block.Motion == "G2"
+
+		switch {
+		case hasI || hasJ:
+			result.Center = Point{result.Start.X + offset('I'), result.Start.Y + offset('J'), 0}
+		case hasR:
+			center, err := arcCenter(result.Start.xy(), result.End.xy(), offset('R'), result.Clockwise)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Center = Point{center.X, center.Y, 0}
+		default:
+			return nil, ErrInvalidArc
+		}
+	case "G5":
+		result.Kind = SegmentBezier
+		_, hasP := block.Param('P')
+		_, hasQ := block.Param('Q')
+
+		switch {
+		case hasI || hasJ:
+			result.Control1 = Point{result.Start.X + offset('I'), result.Start.Y + offset('J'), 0}
+		case m.control2 != nil:
+			// reflection of the previous control point
+			result.Control1 = Point{2*result.Start.X - m.control2.X, 2*result.Start.Y - m.control2.Y, 0}
+		default:
+			return nil, ErrInvalidBezier
+		}
+
+		if !hasP || !hasQ {
+			return nil, ErrInvalidBezier
+		}
+
+		result.Control2 = Point{result.End.X + offset('P'), result.End.Y + offset('Q'), 0}
+		m.control2 = &result.Control2
+	}
+
+	s.Position = result.End
+
+	return result, nil
+}
+
+// arcCenter returns center of an arc of radius r (negative for arcs over 180 degrees) from start to end.
+func arcCenter(start, end geom.Point, r float64, clockwise bool) (geom.Point, error) {
+	d := start.Dist(end)
+	if d == 0 {
+		return geom.Point{}, ErrInvalidArc
+	}
+
+	// 1.0: distance of the center from the chord (clamped to fix rounding of half circles)
+	h := math.Sqrt(math.Max(r*r-d*d/4, 0))
+	if math.Abs(r) < d/2-lengthTolerance {
+		return geom.Point{}, ErrInvalidArc
+	}
+
+	// 1.1: the center of minor clockwise arc is on the right side of the chord
+	dir := end.Sub(start).Mul(1 / d)
+	normal := geom.Pt(-dir.Y, dir.X)
+	if clockwise == (r > 0) {
+		h = -h
+	}
+
+	return start.Add(end).Mul(0.5).Add(normal.Mul(h)), nil
+}
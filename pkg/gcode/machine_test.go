@@ -0,0 +1,118 @@
+package gcode
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMachine_Run(t *testing.T) {
+	tests := []struct {
+		name     string
+		program  string
+		segments int
+		position Point
+	}{
+		{"absolute", "G1 X10 Y5", 1, Point{10, 5, 0}},
+		{"relative", "G91\nG1 X1 Z-2\nX1", 2, Point{2, 0, -2}},
+		{"modal motion after positioning code", "G1 X1\nG91 X10", 2, Point{11, 0, 0}},
+		{"modal motion after plane code", "G1 X1\nG17 Y5", 2, Point{1, 5, 0}},
+		{"no modal motion with M codes", "G1 X10 Y10\nM201 X500 Y500 Z100\nM203 X200 Y200\nM92 X80", 1, Point{10, 10, 0}},
+		{"no modal motion with T codes", "G1 X10\nT1 X5", 1, Point{10, 0, 0}},
+		{"no modal motion with G codes using axis words", "G1 X10\nG53 X5\nG10 L20 P1 X0", 1, Point{10, 0, 0}},
+		{"no modal motion after reset", "X1 Y1", 0, Point{}},
+		{"motion canceled", "G1 X1\nG80\nX5", 1, Point{1, 0, 0}},
+		{"inches", "G20 G1 X1", 1, Point{MMPerInch, 0, 0}},
+		{"G92 offset", "G1 X5\nG92 X0\nX1", 2, Point{6, 0, 0}},
+		{"G92 does not move", "G1 X5\nG92 X0 Y0", 1, Point{5, 0, 0}},
+		{"G28 homes flagged axes", "G1 X5 Y5\nG28 X", 1, Point{0, 5, 0}},
+		{"G28 homes all axes", "G1 X5 Y5 Z1\nG28", 1, Point{}},
+		{"arc", "G2 X10 I5 J0", 1, Point{10, 0, 0}},
+		{"feed only", "G1 F1000", 0, Point{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := []Block{}
+			for i, line := range strings.Split(tt.program, "\n") {
+				block, err := ParseBlock(line)
+				if err != nil {
+					t.Fatalf("line %d: unexpected error: %v", i+1, err)
+				}
+
+				blocks = append(blocks, block)
+			}
+
+			m := NewMachine(NewState())
+			segments, err := m.Run(blocks)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(segments) != tt.segments {
+				t.Errorf("got %d segments, want %d", len(segments), tt.segments)
+			}
+
+			if p := m.State().Position; p != tt.position {
+				t.Errorf("position is %v, want %v", p, tt.position)
+			}
+		})
+	}
+}
+
+func TestSegment_Length(t *testing.T) {
+	tests := []struct {
+		name    string
+		program string
+		length  float64
+	}{
+		{"line", "G1 X3 Y4", 5},
+		{"line with Z", "G1 X3 Z4", 5},
+		{"half circle", "G2 X10 I5 J0", 5 * math.Pi},
+		{"half circle by radius", "G3 X10 R5", 5 * math.Pi},
+		{"full circle", "G1 X5\nG2 X5 I5 J0", 5 + 10*math.Pi},
+		{"helix", "G2 X10 Z-1 I5 J0", math.Hypot(5*math.Pi, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks, err := Parse([]byte(tt.program))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			segments, err := NewMachine(NewState()).Run(blocks)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			length := 0.0
+			for _, s := range segments {
+				length += s.Length()
+			}
+
+			if math.Abs(length-tt.length) > 1e-6 {
+				t.Errorf("length is %v, want %v", length, tt.length)
+			}
+		})
+	}
+}
+
+func TestMachine_Execute_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		program string
+	}{
+		{"arc without center", "G2 X10"},
+		{"arc radius too small", "G2 X10 R1"},
+		{"bezier without control points", "G5 X10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.program)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
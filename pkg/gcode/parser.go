@@ -0,0 +1,231 @@
+// Package gcode reads G-code files written by spiffy and other CAM tools.
+package gcode
+
+import (
+	"strconv"
+	"strings"
+)
+
+// motionCodes are codes setting the motion mode.
+var motionCodes = map[string]bool{
+	"G0": true, "G1": true, "G2": true, "G3": true, "G5": true,
+}
+
+// modalCodes are codes which only set a mode and do not use axis words.
+// Axis words of blocks with no other codes move the machine in the modal motion mode.
+var modalCodes = map[string]bool{
+	"G17": true, "G18": true, "G19": true, "G20": true, "G21": true,
+	"G40": true, "G49": true, "G54": true, "G55": true, "G56": true, "G57": true, "G58": true, "G59": true,
+	"G61": true, "G64": true, "G90": true, "G90.1": true, "G91": true, "G91.1": true, "G93": true, "G94": true,
+}
+
+// Block is a single line of G-code.
+type Block struct {
+	// Line is 1-based line number in the file.
+	Line int
+	// Raw is the line as written (without the line ending).
+	Raw string
+	// Number is the line number (N word) if HasNumber.
+	Number    int
+	HasNumber bool
+	// Deleted is true if the line starts with a block delete ("/").
+	// Such blocks are executed as if the block delete switch was off.
+	Deleted bool
+	// Words are the words of the line as written (except N).
+	Words []Word
+	// Extended is a non-standard command (e.g. Klipper's SET_VELOCITY_LIMIT or LinuxCNC's o100) as written.
+	Extended string
+	// Text is a text argument of Extended or of a text command (e.g. Hello in M117 Hello).
+	Text string
+	// Comments are comments of the line (without ';' and parentheses).
+	Comments []string
+	// Motion is the motion code executed by the block (explicit or modal). Empty if the block does not move.
+	Motion string
+	// State is the machine state after the block.
+	State State
+}
+
+// Codes returns G, M and T words of the block.
+func (b Block) Codes() []Word {
+	result := []Word{}
+	for _, w := range b.Words {
+		if w.IsCode() {
+			result = append(result, w)
+		}
+	}
+
+	return result
+}
+
+// Params returns words of the block which are not codes.
+func (b Block) Params() []Word {
+	result := []Word{}
+	for _, w := range b.Words {
+		if !w.IsCode() {
+			result = append(result, w)
+		}
+	}
+
+	return result
+}
+
+// Param returns the word of the letter.
+func (b Block) Param(letter byte) (Word, bool) {
+	for _, w := range b.Params() {
+		if w.Letter == letter {
+			return w, true
+		}
+	}
+
+	return Word{}, false
+}
+
+// Has returns true if the block has the code (e.g. G90).
+func (b Block) Has(code string) bool {
+	for _, w := range b.Codes() {
+		if w.Code() == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// onlyModalCodes returns true if all codes of the block are modal G codes (see modalCodes).
+func (b Block) onlyModalCodes() bool {
+	for _, w := range b.Codes() {
+		if !modalCodes[w.Code()] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasAxes returns true if the block has X, Y or Z words.
+func (b Block) hasAxes() bool {
+	for _, w := range b.Params() {
+		if strings.IndexByte("XYZ", w.Letter) >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parser reads G-code line by line and tracks the modal state.
+type Parser struct {
+	machine *Machine
+	line    int
+}
+
+// NewParser creates a parser of a machine after reset (see NewState).
+func NewParser() *Parser {
+	return &Parser{machine: NewMachine(NewState())}
+}
+
+// State returns the current modal state.
+func (p *Parser) State() State {
+	return p.machine.State()
+}
+
+// Parse parses the whole file.
+func Parse(data []byte) ([]Block, error) {
+	p := NewParser()
+	lines := strings.Split(string(data), "\n")
+	result := make([]Block, 0, len(lines))
+
+	for _, line := range lines {
+		block, err := p.ParseLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, block)
+	}
+
+	return result, nil
+}
+
+// ParseBlock parses a single line without executing it (Motion and State are not set).
+func ParseBlock(line string) (Block, error) {
+	return newLexer(line, 1).lex()
+}
+
+// ParseLine parses the next line and updates the modal state.
+func (p *Parser) ParseLine(line string) (Block, error) {
+	p.line++
+
+	block, err := newLexer(line, p.line).lex()
+	if err != nil {
+		return Block{}, err
+	}
+
+	if _, err := p.machine.Execute(&block); err != nil {
+		return Block{}, err
+	}
+
+	return block, nil
+}
+
+// repeat returns id and count of LinuxCNC's repeat loop (e.g. o100 repeat [3]).
+func (b Block) repeat() (id string, n int, ok bool, err error) {
+	keyword, count, _ := strings.Cut(b.Text, " ")
+	if b.Extended == "" || toUpper(b.Extended[0]) != 'O' || !strings.EqualFold(keyword, "repeat") {
+		return "", 0, false, nil
+	}
+
+	count = strings.TrimSpace(count)
+	n, err = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(count, "["), "]"))
+	if err != nil {
+		return "", 0, false, &SyntaxError{Line: b.Line, Column: 1, Msg: "unsupported repeat count " + count, Err: ErrSyntax}
+	}
+
+	return strings.ToUpper(b.Extended), n, true, nil
+}
+
+// isEndRepeat returns true if the block ends the repeat loop of id.
+func (b Block) isEndRepeat(id string) bool {
+	return strings.EqualFold(b.Extended, id) && strings.EqualFold(strings.TrimSpace(b.Text), "endrepeat")
+}
+
+// Unroll expands LinuxCNC's repeat loops (o100 repeat [n] ... o100 endrepeat),
+// so that the body is repeated n times. Only constant counts are supported.
+func Unroll(blocks []Block) ([]Block, error) {
+	result := make([]Block, 0, len(blocks))
+	for i := 0; i < len(blocks); i++ {
+		id, n, ok, err := blocks[i].repeat()
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			result = append(result, blocks[i])
+			continue
+		}
+
+		// 1.0: find the end of the loop
+		end := i + 1
+		for end < len(blocks) && !blocks[end].isEndRepeat(id) {
+			end++
+		}
+
+		if end == len(blocks) {
+			return nil, &SyntaxError{Line: blocks[i].Line, Column: 1, Msg: "unclosed " + blocks[i].Extended + " repeat", Err: ErrSyntax}
+		}
+
+		// 1.1: repeat the body (which may contain nested loops)
+		body, err := Unroll(blocks[i+1 : end])
+		if err != nil {
+			return nil, err
+		}
+
+		for k := 0; k < n; k++ {
+			result = append(result, body...)
+		}
+
+		i = end
+	}
+
+	return result, nil
+}
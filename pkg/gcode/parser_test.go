@@ -0,0 +1,185 @@
+package gcode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseBlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		words    string
+		number   int
+		extended string
+		text     string
+		comments []string
+	}{
+		{"spaced", "G0 X10 Y5", "G0 X10 Y5", -1, "", "", nil},
+		{"compact", "G0X10Y5", "G0 X10 Y5", -1, "", "", nil},
+		{"lower case", "g1 x-1.5 y.5 f1200", "G1 X-1.5 Y.5 F1200", -1, "", "", nil},
+		{"flags", "G28 X Y", "G28 X Y", -1, "", "", nil},
+		{"line number and checksum", "N10 G1 X1.50 Y-2*29", "G1 X1.50 Y-2", 10, "", "", nil},
+		{"semicolon comment", "G0 X1 ; move", "G0 X1", -1, "", "", []string{"move"}},
+		{"parenthesised comments", "(start) G0 X1 (first) Y2", "G0 X1 Y2", -1, "", "", []string{"start", "first"}},
+		{"comment only", "; BEGIN PASS", "", -1, "", "", []string{"BEGIN PASS"}},
+		{"text command", "M117 Hello World ; note", "M117", -1, "", "Hello World", []string{"note"}},
+		{"extended command", "SET_VELOCITY_LIMIT ACCEL=2000", "", -1, "SET_VELOCITY_LIMIT", "ACCEL=2000", nil},
+		{"o-word", "o100 repeat [3]", "", -1, "o100", "repeat [3]", nil},
+		{"program delimiter", "%", "", -1, "", "", nil},
+		{"carriage return", "G0 X1\r", "G0 X1", -1, "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block, err := NewParser().ParseLine(tt.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			words := make([]string, len(block.Words))
+			for i, w := range block.Words {
+				words[i] = w.String()
+			}
+
+			if got := strings.Join(words, " "); got != tt.words {
+				t.Errorf("words are %q, want %q", got, tt.words)
+			}
+
+			// number is -1 if the line has no N word
+			if block.HasNumber != (tt.number >= 0) || block.HasNumber && block.Number != tt.number {
+				t.Errorf("line number is %d (%v), want %d", block.Number, block.HasNumber, tt.number)
+			}
+
+			if block.Extended != tt.extended || block.Text != tt.text {
+				t.Errorf("extended and text are %q, %q, want %q, %q", block.Extended, block.Text, tt.extended, tt.text)
+			}
+
+			if strings.Join(block.Comments, "|") != strings.Join(tt.comments, "|") {
+				t.Errorf("comments are %q, want %q", block.Comments, tt.comments)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		err    error
+		line   int
+		column int
+	}{
+		{"unexpected character", "G0 X1\nG1 X1 # Y2", ErrSyntax, 2, 7},
+		{"sign without digits", "G1 X-", ErrSyntax, 1, 5},
+		{"unclosed comment", "G0 (comment", ErrSyntax, 1, 4},
+		{"checksum mismatch", "N10 G1 X1.50 Y-2*30", ErrChecksum, 1, 17},
+		{"invalid checksum", "G1 X1*", ErrSyntax, 1, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected *SyntaxError, got %T", err)
+			}
+
+			if syntaxErr.Line != tt.line || syntaxErr.Column != tt.column {
+				t.Errorf("error at %d:%d, want %d:%d", syntaxErr.Line, syntaxErr.Column, tt.line, tt.column)
+			}
+		})
+	}
+}
+
+func TestParser_State(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		absolute bool
+		inches   bool
+		offset   Point
+		position Point
+	}{
+		{"reset", "", true, false, Point{}, Point{}},
+		{"relative", "G91\nG1 X1\nX1", false, false, Point{}, Point{2, 0, 0}},
+		{"back to absolute", "G91 G1 X1\nG90 X5", true, false, Point{}, Point{5, 0, 0}},
+		{"M code parameters", "G1 X1\nM203 X200", true, false, Point{}, Point{1, 0, 0}},
+		{"inches", "G20\nG0 X1", true, true, Point{}, Point{MMPerInch, 0, 0}},
+		{"G92 offset", "G0 X10\nG92 X0\nG0 X1", true, false, Point{10, 0, 0}, Point{11, 0, 0}},
+		{"G92.1 resets offset", "G0 X10\nG92 X0\nG92.1", true, false, Point{}, Point{10, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser()
+			for _, line := range strings.Split(tt.data, "\n") {
+				if _, err := p.ParseLine(line); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			s := p.State()
+			if s.Absolute != tt.absolute || s.Inches != tt.inches || s.Offset != tt.offset || s.Position != tt.position {
+				t.Errorf("got %+v, want absolute %v, inches %v, offset %v, position %v",
+					s, tt.absolute, tt.inches, tt.offset, tt.position)
+			}
+		})
+	}
+}
+
+func TestUnroll(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"no loops", "G0 X1\nG0 X2", "G0 X1|G0 X2"},
+		{"loop", "o1 repeat [2]\nG0 X1\no1 endrepeat\nG0 X2", "G0 X1|G0 X1|G0 X2"},
+		{"nested loops", "o1 repeat [2]\nG0 X1\no2 repeat [2]\nG0 X2\no2 endrepeat\no1 endrepeat",
+			"G0 X1|G0 X2|G0 X2|G0 X1|G0 X2|G0 X2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			unrolled, err := Unroll(blocks)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			lines := make([]string, len(unrolled))
+			for i, b := range unrolled {
+				lines[i] = b.Raw
+			}
+
+			if got := strings.Join(lines, "|"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Unroll(mustParse(t, "o1 repeat [2]\nG0 X1")); !errors.Is(err, ErrSyntax) {
+		t.Errorf("expected ErrSyntax for an unclosed loop, got %v", err)
+	}
+}
+
+func mustParse(t *testing.T, data string) []Block {
+	t.Helper()
+
+	blocks, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return blocks
+}
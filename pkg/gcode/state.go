@@ -0,0 +1,78 @@
+package gcode
+
+// MMPerInch converts inches (G20) to millimeters.
+const MMPerInch = 25.4
+
+// Point is a position of X, Y and Z axes (in mm).
+type Point struct {
+	X, Y, Z float64
+}
+
+// axis returns pointer to the value of the axis letter (nil for other letters).
+func (p *Point) axis(letter byte) *float64 {
+	switch letter {
+	case 'X':
+		return &p.X
+	case 'Y':
+		return &p.Y
+	case 'Z':
+		return &p.Z
+	}
+
+	return nil
+}
+
+// State is a modal state of the machine.
+type State struct {
+	// Absolute is true in absolute positioning mode (G90) and false in relative one (G91).
+	Absolute bool
+	// Inches is true if coordinates are in inches (G20) and false in millimeters (G21).
+	Inches bool
+	// Motion is the current motion mode (e.g. G1) used by lines with axis words only. Empty after G80.
+	Motion string
+	// Feed is the last feed rate as written (in units per minute).
+	Feed float64
+	// Offset is the offset (in mm) set by G92: machine position = program position + Offset.
+	Offset Point
+	// Position is the machine position (in mm) after the block.
+	Position Point
+}
+
+// NewState returns the state of a machine after reset (absolute, millimeters, at 0,0,0).
+func NewState() State {
+	return State{Absolute: true}
+}
+
+// Unit returns length of the state's unit in mm.
+func (s State) Unit() float64 {
+	if s.Inches {
+		return MMPerInch
+	}
+
+	return 1
+}
+
+// Program converts machine position to program coordinates (in mm).
+func (s State) Program(p Point) Point {
+	return Point{p.X - s.Offset.X, p.Y - s.Offset.Y, p.Z - s.Offset.Z}
+}
+
+// Target returns the machine position (in mm) the words move to from the current position.
+func (s State) Target(words []Word) Point {
+	result := s.Position
+	for _, w := range words {
+		v := result.axis(w.Letter)
+		if v == nil || w.IsFlag() {
+			continue
+		}
+
+		value := w.Value * s.Unit()
+		if s.Absolute {
+			*v = value + *s.Offset.axis(w.Letter)
+		} else {
+			*v += value
+		}
+	}
+
+	return result
+}
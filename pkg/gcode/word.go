@@ -0,0 +1,41 @@
+package gcode
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Word is a letter with a value (e.g. X10.5) or a letter alone (a flag, e.g. X in G28 X).
+type Word struct {
+	// Letter is always upper case.
+	Letter byte
+	Value  float64
+	// Raw is the value as written (empty for flags).
+	Raw string
+	// Column is 1-based position of the letter in the line.
+	Column int
+}
+
+// IsFlag returns true if the word has no value.
+func (w Word) IsFlag() bool {
+	return w.Raw == ""
+}
+
+// IsInt returns true if the value is written without a decimal point.
+func (w Word) IsInt() bool {
+	return w.Raw != "" && !strings.Contains(w.Raw, ".")
+}
+
+// IsCode returns true if the word is a command (G, M or T word).
+func (w Word) IsCode() bool {
+	return !w.IsFlag() && strings.IndexByte("GMT", w.Letter) >= 0
+}
+
+// Code returns normalized command (e.g. G1 for g01).
+func (w Word) Code() string {
+	return string(w.Letter) + strconv.FormatFloat(w.Value, 'f', -1, 64)
+}
+
+func (w Word) String() string {
+	return string(w.Letter) + w.Raw
+}
@@ -0,0 +1,161 @@
+package geom
+
+import "math"
+
+// Arc is an elliptical arc in center parameterization.
+// All angles are in radians. Positive Delta means positive-angle direction
+// (counterclockwise if Y axis points up).
+type Arc struct {
+	Center Point
+	RX, RY float64
+	// Rotation is a rotation of the ellipse's X axis.
+	Rotation float64
+	// Start is an angle of the start point (before applying Rotation).
+	Start float64
+	// Delta is an angular length of the arc.
+	Delta float64
+}
+
+// ArcFromEndpoints converts SVG-like arc (endpoint parameterization) to the center parameterization.
+// rotation is in degrees. Radii are scaled up if they are too small to connect the endpoints.
+// ok is false if the arc should be drawn as a straight line (zero radius) or skipped (p0 == p1).
+// refer: https://www.w3.org/TR/SVG2/implnote.html#ArcConversionEndpointToCenter
+func ArcFromEndpoints(p0, p1 Point, rx, ry, rotation float64, largeArc, sweep bool) (result Arc, ok bool) {
+	if p0 == p1 {
+		return result, false
+	}
+
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return result, false
+	}
+
+	phi := rotation * math.Pi / 180
+	sin, cos := math.Sincos(phi)
+
+	// 1.0: compute (x1', y1')
+	d := p0.Sub(p1).Mul(0.5)
+	x1 := cos*d.X + sin*d.Y
+	y1 := -sin*d.X + cos*d.Y
+
+	// 2.0: ensure radii are large enough
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		s := math.Sqrt(lambda)
+		rx, ry = rx*s, ry*s
+	}
+
+	// 3.0: compute (cx', cy')
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(math.Max(num/den, 0))
+
+	if largeArc == sweep {
+		coef = -coef
+	}
+
+	cx := coef * rx * y1 / ry
+	cy := -coef * ry * x1 / rx
+
+	// 4.0: compute center
+	mid := p0.Add(p1).Mul(0.5)
+	result = Arc{
+		Center:   Pt(cos*cx-sin*cy+mid.X, sin*cx+cos*cy+mid.Y),
+		RX:       rx,
+		RY:       ry,
+		Rotation: phi,
+	}
+
+	// 5.0: compute angles
+	u := Pt((x1-cx)/rx, (y1-cy)/ry)
+	v := Pt((-x1-cx)/rx, (-y1-cy)/ry)
+	result.Start = math.Atan2(u.Y, u.X)
+	result.Delta = math.Mod(math.Atan2(v.Y, v.X)-result.Start, 2*math.Pi)
+
+	switch {
+	case !sweep && result.Delta > 0:
+		result.Delta -= 2 * math.Pi
+	case sweep && result.Delta < 0:
+		result.Delta += 2 * math.Pi
+	}
+
+	return result, true
+}
+
+// CircularArc returns a circular arc with center passing through start and end.
+// If start == end, the arc is a full circle.
+func CircularArc(center, start, end Point, clockwise bool) Arc {
+	a0 := math.Atan2(start.Y-center.Y, start.X-center.X)
+	a1 := math.Atan2(end.Y-center.Y, end.X-center.X)
+	delta := a1 - a0
+
+	switch {
+	case clockwise && delta >= 0:
+		delta -= 2 * math.Pi
+	case !clockwise && delta <= 0:
+		delta += 2 * math.Pi
+	}
+
+	r := center.Dist(start)
+
+	return Arc{
+		Center: center,
+		RX:     r,
+		RY:     r,
+		Start:  a0,
+		Delta:  delta,
+	}
+}
+
+// Point returns a point of the ellipse at the given angle.
+func (a Arc) Point(angle float64) Point {
+	sin, cos := math.Sincos(angle)
+	sinR, cosR := math.Sincos(a.Rotation)
+	x, y := a.RX*cos, a.RY*sin
+
+	return Pt(cosR*x-sinR*y+a.Center.X, sinR*x+cosR*y+a.Center.Y)
+}
+
+// StartPoint returns start point of the arc.
+func (a Arc) StartPoint() Point {
+	return a.Point(a.Start)
+}
+
+// EndPoint returns end point of the arc.
+func (a Arc) EndPoint() Point {
+	return a.Point(a.Start + a.Delta)
+}
+
+// IsCircular returns true if the arc is a part of a circle.
+func (a Arc) IsCircular() bool {
+	return math.Abs(a.RX-a.RY) <= 1e-9*math.Max(a.RX, a.RY)
+}
+
+// Clockwise returns true if the arc goes in negative-angle direction
+// (clockwise if Y axis points up).
+func (a Arc) Clockwise() bool {
+	return a.Delta < 0
+}
+
+// Flatten approximates the arc with a polyline so that distance between the arc and any chord
+// is not greater than tolerance. Result contains both start and end point.
+func (a Arc) Flatten(tolerance float64) []Point {
+	r := math.Max(a.RX, a.RY)
+
+	// 1.0: max angle step for which sagitta (r - r*cos(step/2)) is within tolerance
+	step := math.Pi / 2
+	if tolerance > 0 && tolerance < r {
+		step = math.Min(step, 2*math.Acos(1-tolerance/r))
+	}
+
+	n := int(math.Ceil(math.Abs(a.Delta) / step))
+	if n < 1 {
+		n = 1
+	}
+
+	result := make([]Point, n+1)
+	for i := 0; i <= n; i++ {
+		result[i] = a.Point(a.Start + a.Delta*float64(i)/float64(n))
+	}
+
+	return result
+}
@@ -0,0 +1,73 @@
+package geom
+
+// maxBezierDepth limits recursion of FlattenBezier (2^16 segments is more than enough).
+const maxBezierDepth = 16
+
+// Bezier evaluates a bezier curve of any degree at t (0 <= t <= 1) using de Casteljau's algorithm.
+// points are: start, control points..., end.
+func Bezier(t float64, points ...Point) Point {
+	tmp := make([]Point, len(points))
+	copy(tmp, points)
+
+	for n := len(tmp) - 1; n > 0; n-- {
+		for i := 0; i < n; i++ {
+			tmp[i] = tmp[i].Add(tmp[i+1].Sub(tmp[i]).Mul(t))
+		}
+	}
+
+	return tmp[0]
+}
+
+// SplitBezier splits a bezier curve at t into two curves of the same degree.
+func SplitBezier(t float64, points ...Point) (left, right []Point) {
+	n := len(points)
+	left, right = make([]Point, n), make([]Point, n)
+
+	tmp := make([]Point, n)
+	copy(tmp, points)
+
+	for k := 0; k < n; k++ {
+		left[k] = tmp[0]
+		right[n-1-k] = tmp[n-1-k]
+
+		for i := 0; i < n-1-k; i++ {
+			tmp[i] = tmp[i].Add(tmp[i+1].Sub(tmp[i]).Mul(t))
+		}
+	}
+
+	return left, right
+}
+
+// FlattenBezier approximates a bezier curve of any degree with a polyline
+// so that distance between the curve and the polyline is not greater than tolerance.
+// It subdivides the curve adaptively, so flat parts produce less points than sharp ones.
+// Result contains both start and end point.
+// tolerance must be positive, otherwise every curve is split into 2^maxBezierDepth lines.
+func FlattenBezier(tolerance float64, points ...Point) []Point {
+	result := []Point{points[0]}
+	return flattenBezier(result, tolerance, 0, points)
+}
+
+func flattenBezier(result []Point, tolerance float64, depth int, points []Point) []Point {
+	start, end := points[0], points[len(points)-1]
+
+	// 1.0: curve lies in the convex hull of its control points,
+	// so if all of them are close to the chord, the curve is too.
+	flat := true
+	for _, p := range points[1 : len(points)-1] {
+		if p.DistToSegment(start, end) > tolerance {
+			flat = false
+			break
+		}
+	}
+
+	if flat || depth >= maxBezierDepth {
+		return append(result, end)
+	}
+
+	// 2.0: subdivide
+	left, right := SplitBezier(0.5, points...)
+	result = flattenBezier(result, tolerance, depth+1, left)
+
+	return flattenBezier(result, tolerance, depth+1, right)
+}
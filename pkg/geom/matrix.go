@@ -0,0 +1,82 @@
+package geom
+
+import "math"
+
+// Matrix is a 2D affine transformation matrix (as in SVG):
+//
+//	| A C E |
+//	| B D F |
+//	| 0 0 1 |
+type Matrix struct {
+	A, B, C, D, E, F float64
+}
+
+// Identity returns identity matrix.
+func Identity() Matrix {
+	return Matrix{A: 1, D: 1}
+}
+
+// Translate returns translation matrix.
+func Translate(tx, ty float64) Matrix {
+	return Matrix{A: 1, D: 1, E: tx, F: ty}
+}
+
+// Scale returns scale matrix.
+func Scale(sx, sy float64) Matrix {
+	return Matrix{A: sx, D: sy}
+}
+
+// Rotate returns rotation matrix. angle is in degrees.
+func Rotate(angle float64) Matrix {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	return Matrix{A: cos, B: sin, C: -sin, D: cos}
+}
+
+// SkewX returns skew matrix along X axis. angle is in degrees.
+func SkewX(angle float64) Matrix {
+	return Matrix{A: 1, C: math.Tan(angle * math.Pi / 180), D: 1}
+}
+
+// SkewY returns skew matrix along Y axis. angle is in degrees.
+func SkewY(angle float64) Matrix {
+	return Matrix{A: 1, B: math.Tan(angle * math.Pi / 180), D: 1}
+}
+
+// Mul returns m × n. The result applies n first and then m.
+func (m Matrix) Mul(n Matrix) Matrix {
+	return Matrix{
+		A: m.A*n.A + m.C*n.B,
+		B: m.B*n.A + m.D*n.B,
+		C: m.A*n.C + m.C*n.D,
+		D: m.B*n.C + m.D*n.D,
+		E: m.A*n.E + m.C*n.F + m.E,
+		F: m.B*n.E + m.D*n.F + m.F,
+	}
+}
+
+// Apply transforms point p.
+func (m Matrix) Apply(p Point) Point {
+	return Point{
+		X: m.A*p.X + m.C*p.Y + m.E,
+		Y: m.B*p.X + m.D*p.Y + m.F,
+	}
+}
+
+// ApplyVector transforms vector v (translation is not applied).
+func (m Matrix) ApplyVector(v Point) Point {
+	return Point{
+		X: m.A*v.X + m.C*v.Y,
+		Y: m.B*v.X + m.D*v.Y,
+	}
+}
+
+// Det returns determinant of the linear part of m.
+// Negative determinant means that the transformation mirrors the image.
+func (m Matrix) Det() float64 {
+	return m.A*m.D - m.B*m.C
+}
+
+// IsIdentity returns true if m is an identity matrix.
+func (m Matrix) IsIdentity() bool {
+	return m == Identity()
+}
@@ -0,0 +1,53 @@
+// Package geom contains basic 2D geometry types shared by spiffy packages.
+package geom
+
+import "math"
+
+// Point is a 2D point (or vector) in float64 precision.
+type Point struct {
+	X, Y float64
+}
+
+// Pt is a shorthand for Point{x, y}.
+func Pt(x, y float64) Point {
+	return Point{x, y}
+}
+
+// Add returns p+other.
+func (p Point) Add(other Point) Point {
+	return Point{p.X + other.X, p.Y + other.Y}
+}
+
+// Sub returns p-other.
+func (p Point) Sub(other Point) Point {
+	return Point{p.X - other.X, p.Y - other.Y}
+}
+
+// Mul returns p scaled by scalar.
+func (p Point) Mul(scalar float64) Point {
+	return Point{p.X * scalar, p.Y * scalar}
+}
+
+// Len returns length of the vector p.
+func (p Point) Len() float64 {
+	return math.Hypot(p.X, p.Y)
+}
+
+// Dist returns distance between p and other.
+func (p Point) Dist(other Point) float64 {
+	return p.Sub(other).Len()
+}
+
+// DistToSegment returns distance between p and segment a-b.
+func (p Point) DistToSegment(a, b Point) float64 {
+	ab := b.Sub(a)
+	l2 := ab.X*ab.X + ab.Y*ab.Y
+	if l2 == 0 {
+		return p.Dist(a)
+	}
+
+	t := ((p.X-a.X)*ab.X + (p.Y-a.Y)*ab.Y) / l2
+	t = math.Max(0, math.Min(1, t))
+
+	return p.Dist(a.Add(ab.Mul(t)))
+}
@@ -0,0 +1,54 @@
+package geom
+
+import "math"
+
+// Rect is an axis-aligned rectangle (e.g. a bounding box).
+// Empty rect (see EmptyRect) has Min > Max.
+type Rect struct {
+	Min, Max Point
+}
+
+// EmptyRect returns rect that contains nothing. Extending it by a point results in a rect of that point.
+func EmptyRect() Rect {
+	return Rect{
+		Min: Pt(math.Inf(1), math.Inf(1)),
+		Max: Pt(math.Inf(-1), math.Inf(-1)),
+	}
+}
+
+// IsEmpty returns true if r contains no points.
+func (r Rect) IsEmpty() bool {
+	return r.Min.X > r.Max.X || r.Min.Y > r.Max.Y
+}
+
+// Extend returns the smallest rect containing both r and p.
+func (r Rect) Extend(p Point) Rect {
+	return Rect{
+		Min: Pt(math.Min(r.Min.X, p.X), math.Min(r.Min.Y, p.Y)),
+		Max: Pt(math.Max(r.Max.X, p.X), math.Max(r.Max.Y, p.Y)),
+	}
+}
+
+// Union returns the smallest rect containing both r and other.
+func (r Rect) Union(other Rect) Rect {
+	if other.IsEmpty() {
+		return r
+	}
+
+	return r.Extend(other.Min).Extend(other.Max)
+}
+
+// Width returns width of r.
+func (r Rect) Width() float64 {
+	return math.Max(r.Max.X-r.Min.X, 0)
+}
+
+// Height returns height of r.
+func (r Rect) Height() float64 {
+	return math.Max(r.Max.Y-r.Min.Y, 0)
+}
+
+// Center returns center point of r.
+func (r Rect) Center() Point {
+	return r.Min.Add(r.Max).Mul(0.5)
+}
@@ -0,0 +1,81 @@
+package spiffy
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/kpango/glg"
+
+	"github.com/gucio321/spiffy/pkg/gcb"
+	"github.com/gucio321/spiffy/pkg/geom"
+	"github.com/gucio321/spiffy/pkg/heightmap"
+	"github.com/gucio321/spiffy/pkg/toolpath"
+)
+
+// Heightmap returns GCode forming a part described by the heightmap.
+// The heightmap is scaled to fit the workspace (keeping the aspect ratio) and centered in it.
+// Its deepest points are maxDepth mm deep. Every (up to) stepDown mm the head goes down
+// as Repeat does and draws iso-depth contours of the layer (see (*heightmap.Heightmap).Contours).
+func (s *Spiffy) Heightmap(h *heightmap.Heightmap, maxDepth, stepDown float64) (*gcb.GCodeBuilder, error) {
+	if stepDown <= 0 || maxDepth <= 0 {
+		return nil, fmt.Errorf("depth %v, step down %v: %w", maxDepth, stepDown, ErrInvalidStepDown)
+	}
+
+	builder, err := s.newBuilder()
+	if err != nil {
+		return nil, err
+	}
+
+	if h.Width == 0 || h.Height == 0 {
+		return builder, ErrEmptyHeightmap
+	}
+
+	// 1.0: pixels to workspace
+	width := float64(s.workspace.MaxX - s.workspace.MinX)
+	height := float64(s.workspace.MaxY - s.workspace.MinY)
+	scale := math.Min(width/float64(h.Width), height/float64(h.Height))
+	transform := geom.Translate((width-scale*float64(h.Width))/2, (height-scale*float64(h.Height))/2).
+		Mul(geom.Scale(scale, scale))
+
+	n := int(math.Ceil(maxDepth/stepDown - 1e-9))
+	step := maxDepth / float64(n)
+
+	builder.Commentf("Heightmap %dx%d px (%.3f mm/px): %d layers every %.3f mm", h.Width, h.Height, scale, n, step)
+
+	// 2.0: layers
+	for i := 1; i <= n; i++ {
+		var layer toolpath.Layer
+		for _, contour := range h.Contours(float64(i) / float64(n)) {
+			contour = contour.Transform(transform).Simplify(s.simplify)
+			if contour.Area() != 0 {
+				layer.Paths = append(layer.Paths, toolpath.PathFromPolyline(contour))
+			}
+		}
+
+		if len(layer.Paths) == 0 {
+			glg.Infof("Heightmap has no points deeper than %.3f mm", float64(i)*step)
+			break
+		}
+
+		if s.optimize {
+			start := geom.Pt(float64(builder.Base().X), float64(builder.Base().Y))
+			layer.Paths = toolpath.Optimize(start, layer.Paths)
+		}
+
+		builder.BeginPass()
+		builder.ShiftZ(-1*gcb.RelativePos(step), fmt.Sprintf("Move down to layer %d (%.3f mm)", i, float64(i)*step))
+		if err := toolpath.Emit(builder, layer.Paths...); err != nil {
+			return builder, fmt.Errorf("layer %d: %w", i, err)
+		}
+
+		if err := builder.Move(builder.Base()); err != nil {
+			return builder, fmt.Errorf("cant move to base position: %w", err)
+		}
+	}
+
+	if err := builder.Violations(); err != nil {
+		return builder, err
+	}
+
+	return s.wrap(builder)
+}
@@ -0,0 +1,120 @@
+package heightmap
+
+import (
+	"github.com/gucio321/spiffy/pkg/geom"
+	"github.com/gucio321/spiffy/pkg/toolpath"
+)
+
+// edge is an edge between two neighbour pixels: (X, Y)-(X+1, Y) or (X, Y)-(X, Y+1) if Vertical.
+type edge struct {
+	X, Y     int
+	Vertical bool
+}
+
+// Contours returns closed iso-depth contours at the level (marching squares).
+// Contours separate pixels with depth >= level from the others.
+// Points are in pixels (centers of pixels are at x+0.5, y+0.5).
+// As the heightmap is surrounded by the surface (depth 0), all contours are closed.
+func (h *Heightmap) Contours(level float64) []toolpath.Polyline {
+	inside := func(x, y int) bool {
+		return h.At(x, y) >= level
+	}
+
+	// 1.0: marching squares: find segments of contours in every cell (between 4 pixel centers)
+	neighbours := make(map[edge][]edge)
+	var order []edge
+
+	connect := func(a, b edge) {
+		for _, e := range []edge{a, b} {
+			if _, ok := neighbours[e]; !ok {
+				order = append(order, e)
+			}
+		}
+
+		neighbours[a] = append(neighbours[a], b)
+		neighbours[b] = append(neighbours[b], a)
+	}
+
+	for y := -1; y < h.Height; y++ {
+		for x := -1; x < h.Width; x++ {
+			a, b, c, d := inside(x, y), inside(x+1, y), inside(x+1, y+1), inside(x, y+1)
+			top, right := edge{x, y, false}, edge{x + 1, y, true}
+			bottom, left := edge{x, y + 1, false}, edge{x, y, true}
+
+			// 1.1: crossed edges (clockwise from top)
+			var crossed []edge
+			for _, e := range []struct {
+				edge
+				crossed bool
+			}{{top, a != b}, {right, b != c}, {bottom, c != d}, {left, d != a}} {
+				if e.crossed {
+					crossed = append(crossed, e.edge)
+				}
+			}
+
+			switch len(crossed) {
+			case 2:
+				connect(crossed[0], crossed[1])
+			case 4:
+				// 1.2: saddle: the middle of the cell decides which corners are separated
+				center := (h.At(x, y)+h.At(x+1, y)+h.At(x+1, y+1)+h.At(x, y+1))/4 >= level
+				if a == center {
+					// b and d are separated
+					connect(top, right)
+					connect(bottom, left)
+				} else {
+					// a and c are separated
+					connect(left, top)
+					connect(right, bottom)
+				}
+			}
+		}
+	}
+
+	// 2.0: join segments into closed polylines
+	var result []toolpath.Polyline
+	visited := make(map[edge]bool)
+	for _, start := range order {
+		if visited[start] {
+			continue
+		}
+
+		// every edge has 2 neighbours (contours are closed)
+		var polyline toolpath.Polyline
+		for current, ok := start, true; ok; {
+			visited[current] = true
+			polyline = append(polyline, h.point(current, level))
+
+			ok = false
+			for _, n := range neighbours[current] {
+				if !visited[n] {
+					current, ok = n, true
+					break
+				}
+			}
+		}
+
+		result = append(result, append(polyline, polyline[0]))
+	}
+
+	return result
+}
+
+// point returns a point where the contour at the level crosses the edge.
+func (h *Heightmap) point(e edge, level float64) geom.Point {
+	x1, y1 := e.X+1, e.Y
+	if e.Vertical {
+		x1, y1 = e.X, e.Y+1
+	}
+
+	v0, v1 := h.At(e.X, e.Y), h.At(x1, y1)
+	t := 0.5
+	if v0 != v1 {
+		t = (level - v0) / (v1 - v0)
+	}
+
+	p0 := geom.Pt(float64(e.X)+0.5, float64(e.Y)+0.5)
+	p1 := geom.Pt(float64(x1)+0.5, float64(y1)+0.5)
+
+	return p0.Add(p1.Sub(p0).Mul(t))
+}
@@ -0,0 +1,84 @@
+package heightmap
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+func TestHeightmap_Contours(t *testing.T) {
+	tests := []struct {
+		name     string
+		h        *Heightmap
+		level    float64
+		contours int
+	}{
+		{"empty", &Heightmap{Width: 2, Height: 2, Depth: []float64{0, 0, 0, 0}}, 0.5, 0},
+		{"single pixel", &Heightmap{Width: 1, Height: 1, Depth: []float64{1}}, 0.5, 1},
+		{"too shallow", &Heightmap{Width: 1, Height: 1, Depth: []float64{0.4}}, 0.5, 0},
+		{"full", &Heightmap{Width: 3, Height: 2, Depth: []float64{1, 1, 1, 1, 1, 1}}, 0.5, 1},
+		{"separate pixels", &Heightmap{Width: 3, Height: 1, Depth: []float64{1, 0, 1}}, 0.5, 2},
+		{"ring", &Heightmap{Width: 3, Height: 3, Depth: []float64{1, 1, 1, 1, 0, 1, 1, 1, 1}}, 0.5, 2},
+		// the middle of the saddle cell (0.5) joins the corners...
+		{"saddle joined", &Heightmap{Width: 2, Height: 2, Depth: []float64{1, 0, 0, 1}}, 0.5, 1},
+		// ... or separates them
+		{"saddle separated", &Heightmap{Width: 2, Height: 2, Depth: []float64{1, 0, 0, 1}}, 0.6, 2},
+		{"inverted saddle", &Heightmap{Width: 2, Height: 2, Depth: []float64{0, 1, 1, 0}}, 0.6, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contours := tt.h.Contours(tt.level)
+			if len(contours) != tt.contours {
+				t.Fatalf("got %d contours, want %d: %v", len(contours), tt.contours, contours)
+			}
+
+			for i, c := range contours {
+				if len(c) < 4 || c[0] != c[len(c)-1] {
+					t.Errorf("contour %d is not closed: %v", i, c)
+				}
+			}
+
+			// 1.0: pixels at the level are inside an odd number of contours
+			for y := 0; y < tt.h.Height; y++ {
+				for x := 0; x < tt.h.Width; x++ {
+					n := 0
+					for _, c := range contours {
+						if c.Contains(geom.Pt(float64(x)+0.5, float64(y)+0.5)) {
+							n++
+						}
+					}
+
+					if inside := tt.h.At(x, y) >= tt.level; inside != (n%2 == 1) {
+						t.Errorf("pixel %d,%d (depth %v) is inside %d contours", x, y, tt.h.At(x, y), n)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHeightmap_Contours_Interpolation(t *testing.T) {
+	// a single pixel contour is a diamond crossing edges between pixel centers at the level
+	h := &Heightmap{Width: 1, Height: 1, Depth: []float64{1}}
+	tests := []struct {
+		level float64
+		area  float64
+	}{
+		{0.5, 0.5},
+		{0.25, 2 * 0.75 * 0.75},
+		{1, 0},
+	}
+
+	for _, tt := range tests {
+		contours := h.Contours(tt.level)
+		if len(contours) != 1 {
+			t.Fatalf("level %v: got %d contours, want 1", tt.level, len(contours))
+		}
+
+		if area := math.Abs(contours[0].Area()); math.Abs(area-tt.area) > 1e-9 {
+			t.Errorf("level %v: area is %v, want %v", tt.level, area, tt.area)
+		}
+	}
+}
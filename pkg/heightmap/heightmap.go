@@ -0,0 +1,70 @@
+// Package heightmap converts grayscale images (heightmaps) to iso-depth contours.
+package heightmap
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	// heightmaps are PNG images
+	_ "image/png"
+)
+
+// Heightmap is a grid of relative depths of a part.
+type Heightmap struct {
+	Width, Height int
+	// Depth is a relative depth (0 is the surface, 1 is the deepest point) of pixels row by row.
+	Depth []float64
+}
+
+// FromImage creates a heightmap from the image.
+// Black pixels are the deepest, white (and transparent) ones are the surface.
+// If invert is true, white pixels are the deepest.
+func FromImage(img image.Image, invert bool) *Heightmap {
+	bounds := img.Bounds()
+	result := &Heightmap{
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		Depth:  make([]float64, bounds.Dx()*bounds.Dy()),
+	}
+
+	for y := 0; y < result.Height; y++ {
+		for x := 0; x < result.Width; x++ {
+			c := img.At(bounds.Min.X+x, bounds.Min.Y+y)
+			_, _, _, a := c.RGBA()
+			if a == 0 {
+				continue
+			}
+
+			// 1.0: intensity of the not premultiplied color
+			intensity := float64(color.Gray16Model.Convert(c).(color.Gray16).Y) / float64(a)
+			if invert {
+				intensity = 1 - intensity
+			}
+
+			result.Depth[y*result.Width+x] = 1 - intensity
+		}
+	}
+
+	return result
+}
+
+// Decode decodes a heightmap from a PNG image (see FromImage).
+func Decode(r io.Reader, invert bool) (*Heightmap, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("decoding heightmap: %w", err)
+	}
+
+	return FromImage(img, invert), nil
+}
+
+// At returns relative depth of the pixel (0 outside the heightmap).
+func (h *Heightmap) At(x, y int) float64 {
+	if x < 0 || y < 0 || x >= h.Width || y >= h.Height {
+		return 0
+	}
+
+	return h.Depth[y*h.Width+x]
+}
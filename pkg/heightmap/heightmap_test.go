@@ -0,0 +1,44 @@
+package heightmap
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestFromImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	img.Set(0, 0, color.NRGBA{0, 0, 0, 255})
+	img.Set(1, 0, color.NRGBA{255, 255, 255, 255})
+	img.Set(2, 0, color.NRGBA{0, 0, 0, 128})
+	// (3, 0) is transparent
+
+	tests := []struct {
+		name   string
+		invert bool
+		want   []float64
+	}{
+		{"black is deep", false, []float64{1, 0, 1, 0}},
+		{"white is deep", true, []float64{0, 1, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := FromImage(img, tt.invert)
+			if h.Width != 4 || h.Height != 1 {
+				t.Fatalf("size is %dx%d, want 4x1", h.Width, h.Height)
+			}
+
+			for x, want := range tt.want {
+				if got := h.At(x, 0); math.Abs(got-want) > 1e-3 {
+					t.Errorf("pixel %d: depth is %v, want %v", x, got, want)
+				}
+			}
+
+			if h.At(-1, 0) != 0 || h.At(4, 0) != 0 {
+				t.Error("pixels outside the heightmap should be at the surface")
+			}
+		})
+	}
+}
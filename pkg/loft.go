@@ -0,0 +1,114 @@
+package spiffy
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gucio321/spiffy/pkg/gcb"
+	"github.com/gucio321/spiffy/pkg/toolpath"
+)
+
+// Outlines returns top and bottom outline of a lofted part (see Loft).
+// top and bottom are ids or labels of paths or layers (groups); the first closed path
+// of each of them is used. If both are empty, the first two closed paths of the document are used.
+func (s *Spiffy) Outlines(top, bottom string) (topOutline, bottomOutline toolpath.Polyline, err error) {
+	if top == "" && bottom == "" {
+		outlines, err := s.closedPaths(s.doc, "document")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(outlines) < 2 {
+			return nil, nil, fmt.Errorf("document has %d closed paths: %w", len(outlines), ErrNoOutline)
+		}
+
+		return outlines[0], outlines[1], nil
+	}
+
+	result := make([]toolpath.Polyline, 2)
+	for i, name := range []string{top, bottom} {
+		e := s.doc.find(name)
+		if e == nil {
+			return nil, nil, fmt.Errorf("%s: element not found: %w", name, ErrNoOutline)
+		}
+
+		outlines, err := s.closedPaths(e, name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(outlines) == 0 {
+			return nil, nil, fmt.Errorf("%s: no closed paths: %w", name, ErrNoOutline)
+		}
+
+		result[i] = outlines[0]
+	}
+
+	return result[0], result[1], nil
+}
+
+// closedPaths returns closed paths (with area) of the element as polylines.
+func (s *Spiffy) closedPaths(e *element, name string) (result []toolpath.Polyline, err error) {
+	layer, err := s.toolpathOf(e)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	for _, p := range layer.Paths {
+		if !p.IsClosed(0) {
+			continue
+		}
+
+		if polyline := p.Polyline(s.tolerance); polyline.Area() != 0 {
+			result = append(result, polyline)
+		}
+	}
+
+	return result, nil
+}
+
+// Loft returns GCode forming a part which top outline morphs into the bottom one (see Outlines).
+// Contours are drawn every stepDown mm (it is decreased, so that the last contour is exactly at depth)
+// going down between them as Repeat does.
+func (s *Spiffy) Loft(top, bottom string, depth, stepDown float64) (*gcb.GCodeBuilder, error) {
+	if stepDown <= 0 || depth <= 0 {
+		return nil, fmt.Errorf("depth %v, step down %v: %w", depth, stepDown, ErrInvalidStepDown)
+	}
+
+	builder, err := s.newBuilder()
+	if err != nil {
+		return nil, err
+	}
+
+	topOutline, bottomOutline, err := s.Outlines(top, bottom)
+	if err != nil {
+		return builder, err
+	}
+
+	n := int(math.Ceil(depth/stepDown - 1e-9))
+	step := depth / float64(n)
+	contours := toolpath.Loft(topOutline, bottomOutline, n)
+
+	builder.Commentf("Lofting %d contours every %.3f mm", len(contours), step)
+
+	for i, contour := range contours {
+		builder.BeginPass()
+		if i > 0 {
+			builder.ShiftZ(-1*gcb.RelativePos(step), "Move down to the next contour")
+		}
+
+		if err := toolpath.Emit(builder, toolpath.PathFromPolyline(contour).Simplify(s.simplify)); err != nil {
+			return builder, fmt.Errorf("contour %d: %w", i, err)
+		}
+	}
+
+	if err := builder.Move(builder.Base()); err != nil {
+		return builder, fmt.Errorf("cant move to base position: %w", err)
+	}
+
+	if err := builder.Violations(); err != nil {
+		return builder, err
+	}
+
+	return s.wrap(builder)
+}
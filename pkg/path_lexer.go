@@ -0,0 +1,157 @@
+package spiffy
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// pathLexer tokenizes SVG path data (the "d" attribute).
+// See https://www.w3.org/TR/SVG2/paths.html#PathDataBNF
+//
+// Numbers may be written in a compact form where separators are omitted
+// as long as it is unambiguous, e.g. "1.5.5" is 1.5 and .5; "-1-2" is -1 and -2.
+// Arc flags may also be written without separators ("a1 1 0 101 1").
+type pathLexer struct {
+	data string
+	pos  int
+}
+
+func newPathLexer(data string) *pathLexer {
+	return &pathLexer{data: data}
+}
+
+func isPathSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// skipSeparators skips whitespaces and at most one comma.
+func (l *pathLexer) skipSeparators() {
+	comma := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPathSpace(c):
+		case c == ',' && !comma:
+			comma = true
+		default:
+			return
+		}
+
+		l.pos++
+	}
+}
+
+func (l *pathLexer) eof() bool {
+	l.skipSeparators()
+	return l.pos >= len(l.data)
+}
+
+// command returns a command letter if the next token is a command.
+func (l *pathLexer) command() (PathType, bool) {
+	if l.eof() {
+		return END, false
+	}
+
+	t, ok := PathTypeEnum[l.data[l.pos:l.pos+1]]
+	if !ok {
+		return END, false
+	}
+
+	l.pos++
+
+	return t, true
+}
+
+// hasNumber returns true if the next token looks like a number.
+func (l *pathLexer) hasNumber() bool {
+	if l.eof() {
+		return false
+	}
+
+	c := l.data[l.pos]
+
+	return isDigit(c) || c == '-' || c == '+' || c == '.'
+}
+
+// number reads a floating point number.
+func (l *pathLexer) number() (float64, error) {
+	l.skipSeparators()
+	start := l.pos
+
+	// 1.0: sign
+	if l.pos < len(l.data) && (l.data[l.pos] == '-' || l.data[l.pos] == '+') {
+		l.pos++
+	}
+
+	// 1.1: integer part
+	digits := 0
+	for l.pos < len(l.data) && isDigit(l.data[l.pos]) {
+		l.pos++
+		digits++
+	}
+
+	// 1.2: fraction (only one dot is allowed, the next one starts a new number)
+	if l.pos < len(l.data) && l.data[l.pos] == '.' {
+		l.pos++
+		for l.pos < len(l.data) && isDigit(l.data[l.pos]) {
+			l.pos++
+			digits++
+		}
+	}
+
+	if digits == 0 {
+		return 0, l.errorf(start, "expected number")
+	}
+
+	// 1.3: exponent
+	if l.pos < len(l.data) && (l.data[l.pos] == 'e' || l.data[l.pos] == 'E') {
+		expStart := l.pos
+		l.pos++
+		if l.pos < len(l.data) && (l.data[l.pos] == '-' || l.data[l.pos] == '+') {
+			l.pos++
+		}
+
+		expDigits := 0
+		for l.pos < len(l.data) && isDigit(l.data[l.pos]) {
+			l.pos++
+			expDigits++
+		}
+
+		// not an exponent - leave it for the next token
+		if expDigits == 0 {
+			l.pos = expStart
+		}
+	}
+
+	result, err := strconv.ParseFloat(l.data[start:l.pos], 64)
+	if err != nil {
+		return 0, l.errorf(start, "invalid number %q", l.data[start:l.pos])
+	}
+
+	return result, nil
+}
+
+// flag reads an arc flag (single 0 or 1 character).
+func (l *pathLexer) flag() (bool, error) {
+	l.skipSeparators()
+	if l.pos >= len(l.data) {
+		return false, l.errorf(l.pos, "expected flag, got end of data")
+	}
+
+	c := l.data[l.pos]
+	switch c {
+	case '0', '1':
+		l.pos++
+		return c == '1', nil
+	}
+
+	return false, l.errorf(l.pos, "expected flag (0 or 1), got %q", c)
+}
+
+func (l *pathLexer) errorf(pos int, format string, args ...any) error {
+	return fmt.Errorf("%s at position %d: %w", fmt.Sprintf(format, args...), pos, ErrInvalidPathData)
+}
@@ -0,0 +1,111 @@
+package spiffy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathCommand is a single command from SVG path data as written in the file.
+// Args contains exactly Type.NArgs() numbers. For arcs, flags are stored as 0 or 1.
+type PathCommand struct {
+	Type PathType
+	Args []float64
+}
+
+func (c PathCommand) String() string {
+	args := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		args[i] = fmt.Sprintf("%v", a)
+	}
+
+	return strings.TrimSpace(c.Type.String() + " " + strings.Join(args, " "))
+}
+
+// PathData is a parsed content of the "d" attribute.
+type PathData []PathCommand
+
+// ParsePathData parses SVG path data (content of the "d" attribute).
+// Repeated commands are split, so e.g. "M0 0 10 10 20 20" results in
+// M 0 0, L 10 10, L 20 20 (subsequent pairs after moveto are implicit lineto's).
+func ParsePathData(d string) (PathData, error) {
+	l := newPathLexer(d)
+	result := PathData{}
+
+	// 1.0: path data must start with moveto
+	if l.eof() {
+		return result, nil
+	}
+
+	cmd, ok := l.command()
+	if !ok {
+		return nil, l.errorf(l.pos, "expected command")
+	}
+
+	if cmd.Abs() != PathMoveToAbs {
+		return nil, l.errorf(l.pos-1, "path data must start with moveto, got %v", cmd)
+	}
+
+	// 2.0: read commands
+	for {
+		// 2.1: closepath takes no args
+		if cmd.NArgs() == 0 {
+			result = append(result, PathCommand{Type: cmd})
+		} else {
+			// 2.2: read at least one set of args and then as many as possible
+			for first := true; first || l.hasNumber(); first = false {
+				args, err := readPathArgs(l, cmd)
+				if err != nil {
+					return nil, fmt.Errorf("reading arguments of %v: %w", cmd, err)
+				}
+
+				result = append(result, PathCommand{Type: cmd, Args: args})
+
+				// 2.3: subsequent pairs after moveto are lineto's
+				switch cmd {
+				case PathMoveToAbs:
+					cmd = PathLineToAbs
+				case PathMoveToRel:
+					cmd = PathLineToRel
+				}
+			}
+		}
+
+		if l.eof() {
+			break
+		}
+
+		if cmd, ok = l.command(); !ok {
+			return nil, l.errorf(l.pos, "expected command, got %q", l.data[l.pos])
+		}
+	}
+
+	return result, nil
+}
+
+func readPathArgs(l *pathLexer, cmd PathType) ([]float64, error) {
+	args := make([]float64, cmd.NArgs())
+	for i := range args {
+		// large-arc-flag and sweep-flag
+		if cmd.Abs() == PathEllipticalArcAbs && (i == 3 || i == 4) {
+			flag, err := l.flag()
+			if err != nil {
+				return nil, err
+			}
+
+			if flag {
+				args[i] = 1
+			}
+
+			continue
+		}
+
+		n, err := l.number()
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = n
+	}
+
+	return args, nil
+}
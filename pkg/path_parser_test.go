@@ -0,0 +1,147 @@
+package spiffy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+func TestParsePathData(t *testing.T) {
+	tests := []struct {
+		name string
+		d    string
+		want string
+	}{
+		{"empty", "", ""},
+		{"whitespace only", " \n\t", ""},
+		{"simple", "M 10 20 L 30 40 Z", "M 10 20; L 30 40; Z"},
+		{"commas", "M10,20L30,40", "M 10 20; L 30 40"},
+		{"implicit lineto after moveto", "M0 0 10 10 20 20", "M 0 0; L 10 10; L 20 20"},
+		{"implicit relative lineto", "m1 1 2 2", "m 1 1; l 2 2"},
+		{"repeated command", "M0 0 H1 2 3", "M 0 0; H 1; H 2; H 3"},
+		{"compact dots", "M1.5.5", "M 1.5 0.5"},
+		{"compact signs", "M-1-2", "M -1 -2"},
+		{"exponent", "M1e2-1E-1", "M 100 -0.1"},
+		{"compact arc flags", "M0 0a1 1 0 101 1", "M 0 0; a 1 1 0 1 0 1 1"},
+		{"all commands", "M0 0 C1 1 2 2 3 3 S4 4 5 5 Q6 6 7 7 T8 8 A1 2 30 0 1 9 9 z",
+			"M 0 0; C 1 1 2 2 3 3; S 4 4 5 5; Q 6 6 7 7; T 8 8; A 1 2 30 0 1 9 9; z"},
+		{"subpaths", "M0 0L1 1ZM2 2l1 1z", "M 0 0; L 1 1; Z; M 2 2; l 1 1; z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ParsePathData(tt.d)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			commands := make([]string, len(data))
+			for i, c := range data {
+				commands[i] = c.String()
+			}
+
+			if got := strings.Join(commands, "; "); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePathData_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		d    string
+	}{
+		{"not starting with moveto", "L 10 10"},
+		{"missing argument", "M 10"},
+		{"unknown command", "M 0 0 X 10"},
+		{"invalid flag", "M0 0 A1 1 0 2 0 1 1"},
+		{"double comma", "M 0,,0"},
+		{"sign only", "M 0 - 1"},
+		{"e without exponent digits", "M1 2v1e"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePathData(tt.d); !errors.Is(err, ErrInvalidPathData) {
+				t.Errorf("expected ErrInvalidPathData, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPathData_Segments(t *testing.T) {
+	tests := []struct {
+		name string
+		d    string
+		want []Segment
+	}{
+		{
+			"relative and horizontal/vertical lines",
+			"m1 1 h2 v3 l-1 -1 z",
+			[]Segment{
+				{Kind: SegmentMove, End: geom.Pt(1, 1)},
+				{Kind: SegmentLine, Start: geom.Pt(1, 1), End: geom.Pt(3, 1)},
+				{Kind: SegmentLine, Start: geom.Pt(3, 1), End: geom.Pt(3, 4)},
+				{Kind: SegmentLine, Start: geom.Pt(3, 4), End: geom.Pt(2, 3)},
+				{Kind: SegmentClose, Start: geom.Pt(2, 3), End: geom.Pt(1, 1)},
+			},
+		},
+		{
+			"smooth cubic reflects the control point",
+			"M0 0 C0 1 2 1 2 0 S4 -1 4 0",
+			[]Segment{
+				{Kind: SegmentMove},
+				{Kind: SegmentCubic, C1: geom.Pt(0, 1), C2: geom.Pt(2, 1), End: geom.Pt(2, 0)},
+				{Kind: SegmentCubic, Start: geom.Pt(2, 0), C1: geom.Pt(2, -1), C2: geom.Pt(4, -1), End: geom.Pt(4, 0)},
+			},
+		},
+		{
+			"smooth cubic without previous curve",
+			"M0 0 S1 1 2 0",
+			[]Segment{
+				{Kind: SegmentMove},
+				{Kind: SegmentCubic, C1: geom.Pt(0, 0), C2: geom.Pt(1, 1), End: geom.Pt(2, 0)},
+			},
+		},
+		{
+			"smooth quadratic reflects the control point",
+			"M0 0 Q1 1 2 0 t2 0",
+			[]Segment{
+				{Kind: SegmentMove},
+				{Kind: SegmentQuadratic, C1: geom.Pt(1, 1), End: geom.Pt(2, 0)},
+				{Kind: SegmentQuadratic, Start: geom.Pt(2, 0), C1: geom.Pt(3, -1), End: geom.Pt(4, 0)},
+			},
+		},
+		{
+			"arc",
+			"M0 0 a5 5 0 1 0 10 0",
+			[]Segment{
+				{Kind: SegmentMove},
+				{Kind: SegmentArc, End: geom.Pt(10, 0), Arc: ArcParams{RX: 5, RY: 5, LargeArc: true}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ParsePathData(tt.d)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := data.Segments()
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d segments, want %d", len(got), len(tt.want))
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("segment %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package spiffy
+
+import (
+	"math"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+// SegmentKind is a kind of a normalized path segment.
+type SegmentKind int
+
+const (
+	// SegmentMove starts a new subpath at End.
+	SegmentMove SegmentKind = iota
+	// SegmentLine is a straight line from Start to End.
+	SegmentLine
+	// SegmentQuadratic is a quadratic bezier curve from Start to End with control point C1.
+	SegmentQuadratic
+	// SegmentCubic is a cubic bezier curve from Start to End with control points C1 and C2.
+	SegmentCubic
+	// SegmentArc is an elliptical arc from Start to End described by Arc.
+	SegmentArc
+	// SegmentClose is a line closing the subpath (End is the subpath start point).
+	SegmentClose
+)
+
+// ArcParams are parameters of SVG elliptical arc in endpoint parameterization.
+type ArcParams struct {
+	RX, RY float64
+	// XAxisRotation is in degrees.
+	XAxisRotation float64
+	LargeArc      bool
+	Sweep         bool
+}
+
+// Segment is a normalized path segment. All points are absolute.
+type Segment struct {
+	Kind       SegmentKind
+	Start, End geom.Point
+	C1, C2     geom.Point
+	Arc        ArcParams
+}
+
+// Segments converts path data to the list of normalized segments:
+// - all coordinates are absolute
+// - H/V are converted to lines
+// - S/T are converted to cubic/quadratic curves with reflected control points.
+func (d PathData) Segments() []Segment {
+	result := make([]Segment, 0, len(d))
+
+	var current, subpathStart, lastControl geom.Point
+
+	lastType := END
+
+	for _, cmd := range d {
+		var base geom.Point
+		if cmd.Type.IsRelative() {
+			base = current
+		}
+
+		pt := func(i int) geom.Point {
+			return base.Add(geom.Pt(cmd.Args[i], cmd.Args[i+1]))
+		}
+
+		seg := Segment{Start: current}
+
+		switch cmd.Type.Abs() {
+		case PathMoveToAbs:
+			seg.Kind = SegmentMove
+			seg.End = pt(0)
+			subpathStart = seg.End
+		case PathLineToAbs:
+			seg.Kind = SegmentLine
+			seg.End = pt(0)
+		case PathLineToHorizontalAbs:
+			seg.Kind = SegmentLine
+			seg.End = geom.Pt(base.X+cmd.Args[0], current.Y)
+		case PathLineToVerticalAbs:
+			seg.Kind = SegmentLine
+			seg.End = geom.Pt(current.X, base.Y+cmd.Args[0])
+		case PathCubicBezierCurveAbs:
+			seg.Kind = SegmentCubic
+			seg.C1, seg.C2, seg.End = pt(0), pt(2), pt(4)
+		case PathSmoothCubicBezierCurveAbs:
+			seg.Kind = SegmentCubic
+			seg.C1 = current
+			if a := lastType.Abs(); a == PathCubicBezierCurveAbs || a == PathSmoothCubicBezierCurveAbs {
+				seg.C1 = reflect(lastControl, current)
+			}
+
+			seg.C2, seg.End = pt(0), pt(2)
+		case PathQuadraticBezierCurveAbs:
+			seg.Kind = SegmentQuadratic
+			seg.C1, seg.End = pt(0), pt(2)
+		case PathSmoothQuadraticBezierCurveAbs:
+			seg.Kind = SegmentQuadratic
+			seg.C1 = current
+			if a := lastType.Abs(); a == PathQuadraticBezierCurveAbs || a == PathSmoothQuadraticBezierCurveAbs {
+				seg.C1 = reflect(lastControl, current)
+			}
+
+			seg.End = pt(0)
+		case PathEllipticalArcAbs:
+			seg.Kind = SegmentArc
+			seg.Arc = ArcParams{
+				RX:            cmd.Args[0],
+				RY:            cmd.Args[1],
+				XAxisRotation: cmd.Args[2],
+				LargeArc:      cmd.Args[3] != 0,
+				Sweep:         cmd.Args[4] != 0,
+			}
+			seg.End = pt(5)
+		case PathCloseAbs:
+			seg.Kind = SegmentClose
+			seg.End = subpathStart
+		}
+
+		// last control point is needed for S and T
+		switch seg.Kind {
+		case SegmentCubic:
+			lastControl = seg.C2
+		case SegmentQuadratic:
+			lastControl = seg.C1
+		}
+
+		current = seg.End
+		lastType = cmd.Type
+		result = append(result, seg)
+	}
+
+	return result
+}
+
+// CenterArc converts SegmentArc to the center parameterization.
+// ok is false if the arc is degenerated (zero radius or start == end).
+func (s Segment) CenterArc() (arc geom.Arc, ok bool) {
+	return geom.ArcFromEndpoints(s.Start, s.End, s.Arc.RX, s.Arc.RY, s.Arc.XAxisRotation, s.Arc.LargeArc, s.Arc.Sweep)
+}
+
+// Transform returns segment transformed by m.
+func (s Segment) Transform(m geom.Matrix) Segment {
+	s.Start, s.End = m.Apply(s.Start), m.Apply(s.End)
+	s.C1, s.C2 = m.Apply(s.C1), m.Apply(s.C2)
+
+	if s.Kind == SegmentArc {
+		s.Arc = s.Arc.Transform(m)
+	}
+
+	return s
+}
+
+// Transform returns arc parameters of the ellipse transformed by m.
+// Translation does not affect arc parameters.
+func (a ArcParams) Transform(m geom.Matrix) ArcParams {
+	// 1.0: ellipse is a unit circle transformed by E = m * rotate(phi) * scale(rx, ry).
+	e := geom.Matrix{A: m.A, B: m.B, C: m.C, D: m.D}.
+		Mul(geom.Rotate(a.XAxisRotation)).
+		Mul(geom.Scale(a.RX, a.RY))
+
+	// 2.0: axes of the new ellipse are eigenvectors of E * E^T
+	// and radii are square roots of its eigenvalues.
+	p := e.A*e.A + e.C*e.C
+	q := e.A*e.B + e.C*e.D
+	r := e.B*e.B + e.D*e.D
+
+	mean := (p + r) / 2
+	delta := math.Hypot((p-r)/2, q)
+
+	a.RX = math.Sqrt(mean + delta)
+	a.RY = math.Sqrt(math.Max(mean-delta, 0))
+	a.XAxisRotation = math.Atan2(2*q, p-r) / 2 * 180 / math.Pi
+
+	// 3.0: mirroring changes direction of the arc.
+	if m.Det() < 0 {
+		a.Sweep = !a.Sweep
+	}
+
+	return a
+}
+
+// reflect returns reflection of p relative to center.
+func reflect(p, center geom.Point) geom.Point {
+	return center.Mul(2).Sub(p)
+}
@@ -25,8 +25,26 @@ const (
 	PathCubicBezierCurveAbs // C
 	// c - cubic bezier curve to relative pos (3 args)(controlPoint1, controlPoint2, endPoint)
 	PathCubicBezierCurveRel // c
-	PathCloseAbs            // Z
-	PathCloseRel            // z
+	// S - smooth cubic bezier curve to absolute pos (2 args)(controlPoint2, endPoint)
+	// controlPoint1 is a reflection of the previous curve's controlPoint2
+	PathSmoothCubicBezierCurveAbs // S
+	// s - smooth cubic bezier curve to relative pos (2 args)
+	PathSmoothCubicBezierCurveRel // s
+	// Q - quadratic bezier curve to absolute pos (2 args)(controlPoint, endPoint)
+	PathQuadraticBezierCurveAbs // Q
+	// q - quadratic bezier curve to relative pos (2 args)
+	PathQuadraticBezierCurveRel // q
+	// T - smooth quadratic bezier curve to absolute pos (1 arg)
+	// controlPoint is a reflection of the previous curve's controlPoint
+	PathSmoothQuadraticBezierCurveAbs // T
+	// t - smooth quadratic bezier curve to relative pos (1 arg)
+	PathSmoothQuadraticBezierCurveRel // t
+	// A - elliptical arc to absolute pos (rx ry x-axis-rotation large-arc-flag sweep-flag x y)
+	PathEllipticalArcAbs // A
+	// a - elliptical arc to relative pos
+	PathEllipticalArcRel // a
+	PathCloseAbs         // Z
+	PathCloseRel         // z
 	END
 )
 
@@ -37,3 +55,35 @@ var PathTypeEnum = func() map[string]PathType {
 	}
 	return m
 }()
+
+// IsRelative returns true for lowercase (relative) commands.
+func (p PathType) IsRelative() bool {
+	return p != END && p%2 == 1
+}
+
+// Abs returns absolute variant of the command.
+func (p PathType) Abs() PathType {
+	if p.IsRelative() {
+		return p - 1
+	}
+
+	return p
+}
+
+// NArgs returns number of numbers taken by a single occurrence of the command.
+func (p PathType) NArgs() int {
+	switch p.Abs() {
+	case PathMoveToAbs, PathLineToAbs, PathSmoothQuadraticBezierCurveAbs:
+		return 2
+	case PathLineToHorizontalAbs, PathLineToVerticalAbs:
+		return 1
+	case PathCubicBezierCurveAbs:
+		return 6
+	case PathSmoothCubicBezierCurveAbs, PathQuadraticBezierCurveAbs:
+		return 4
+	case PathEllipticalArcAbs:
+		return 7
+	}
+
+	return 0
+}
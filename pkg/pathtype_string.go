@@ -18,14 +18,22 @@ func _() {
 	_ = x[PathLineToVerticalRel-7]
 	_ = x[PathCubicBezierCurveAbs-8]
 	_ = x[PathCubicBezierCurveRel-9]
-	_ = x[PathCloseAbs-10]
-	_ = x[PathCloseRel-11]
-	_ = x[END-12]
+	_ = x[PathSmoothCubicBezierCurveAbs-10]
+	_ = x[PathSmoothCubicBezierCurveRel-11]
+	_ = x[PathQuadraticBezierCurveAbs-12]
+	_ = x[PathQuadraticBezierCurveRel-13]
+	_ = x[PathSmoothQuadraticBezierCurveAbs-14]
+	_ = x[PathSmoothQuadraticBezierCurveRel-15]
+	_ = x[PathEllipticalArcAbs-16]
+	_ = x[PathEllipticalArcRel-17]
+	_ = x[PathCloseAbs-18]
+	_ = x[PathCloseRel-19]
+	_ = x[END-20]
 }
 
-const _PathType_name = "MmLlHhVvCcZzEND"
+const _PathType_name = "MmLlHhVvCcSsQqTtAaZzEND"
 
-var _PathType_index = [...]uint8{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 15}
+var _PathType_index = [...]uint8{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 23}
 
 func (i PathType) String() string {
 	if i < 0 || i >= PathType(len(_PathType_index)-1) {
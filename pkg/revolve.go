@@ -0,0 +1,203 @@
+package spiffy
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gucio321/spiffy/pkg/gcb"
+	"github.com/gucio321/spiffy/pkg/geom"
+	"github.com/gucio321/spiffy/pkg/toolpath"
+)
+
+// Contour is a circular contour of a revolved profile (see Revolve).
+type Contour struct {
+	// Depth is how much deeper (in mm) than the first contour the contour is drawn.
+	Depth  float64
+	Radius float64
+}
+
+// Contours samples the half-profile of an axisymmetric part every stepDown mm.
+// The profile is the first path of the document: its X is a radius
+// (distance from the left edge of the document) and Y is a depth
+// (growing downwards as in SVG, from the top of the profile).
+// Depths where the profile has no positive radius are skipped.
+func (s *Spiffy) Contours(stepDown float64) ([]Contour, error) {
+	if stepDown <= 0 {
+		return nil, fmt.Errorf("step down %v: %w", stepDown, ErrInvalidStepDown)
+	}
+
+	layer, err := s.Toolpath()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(layer.Paths) == 0 {
+		return nil, ErrNoProfile
+	}
+
+	profile := layer.Paths[0].Polyline(s.tolerance)
+	bbox := profile.BBox()
+
+	var result []Contour
+	for depth := 0.0; depth <= bbox.Height()+1e-9; depth += stepDown {
+		if r, ok := radiusAt(profile, bbox.Min.Y+depth); ok && r > 0 {
+			result = append(result, Contour{Depth: depth, Radius: r})
+		}
+	}
+
+	return result, nil
+}
+
+// radiusAt returns the biggest X of the profile at y.
+func radiusAt(profile toolpath.Polyline, y float64) (result float64, ok bool) {
+	for i := 1; i < len(profile); i++ {
+		a, b := profile[i-1], profile[i]
+		if (y < a.Y && y < b.Y) || (y > a.Y && y > b.Y) {
+			continue
+		}
+
+		x := math.Max(a.X, b.X)
+		if a.Y != b.Y {
+			x = a.X + (y-a.Y)/(b.Y-a.Y)*(b.X-a.X)
+		}
+
+		if !ok || x > result {
+			result, ok = x, true
+		}
+	}
+
+	return result, ok
+}
+
+// Center sets an axis of a revolved part (see Revolve) in printer's coordinates
+// (as workspace's BaseX/BaseY). Default is the middle of the workspace.
+func (s *Spiffy) Center(x, y float64) *Spiffy {
+	s.center = &geom.Point{X: x, Y: y}
+	return s
+}
+
+// Revolve returns GCode forming an axisymmetric part: a circle (see (*gcb.GCodeBuilder).DrawCircle)
+// around the center (see Center) for every contour of the profile (see Contours).
+// If spiral is true, the head does not go up between contours. Instead it goes down
+// gradually along a quarter-turn spiral to the next contour.
+func (s *Spiffy) Revolve(stepDown float64, spiral bool) (*gcb.GCodeBuilder, error) {
+	builder, err := s.newBuilder()
+	if err != nil {
+		return nil, err
+	}
+
+	center := gcb.BetterPt(
+		gcb.AbsolutePos(s.workspace.MaxX-s.workspace.MinX)/2,
+		gcb.AbsolutePos(s.workspace.MaxY-s.workspace.MinY)/2,
+	)
+
+	if s.center != nil {
+		center = gcb.BetterPt(
+			gcb.AbsolutePos(s.center.X-float64(s.workspace.MinX)),
+			gcb.AbsolutePos(s.center.Y-float64(s.workspace.MinY)),
+		)
+	}
+
+	contours, err := s.Contours(stepDown)
+	if err != nil {
+		return builder, err
+	}
+
+	builder.Commentf("Revolving profile: %d contours around %v", len(contours), center)
+
+	if spiral {
+		err = revolveSpiral(builder, center, contours)
+	} else {
+		err = revolveStepped(builder, center, contours)
+	}
+
+	if err != nil {
+		return builder, err
+	}
+
+	if err := builder.Move(builder.Base()); err != nil {
+		return builder, fmt.Errorf("cant move to base position: %w", err)
+	}
+
+	if err := builder.Violations(); err != nil {
+		return builder, err
+	}
+
+	return s.wrap(builder)
+}
+
+// revolveStepped draws contours as separate circles going down between them (with the head up).
+func revolveStepped(builder *gcb.GCodeBuilder, center gcb.BetterPoint[gcb.AbsolutePos], contours []Contour) error {
+	for i, c := range contours {
+		builder.BeginPass()
+		if i > 0 {
+			builder.ShiftZ(-1*gcb.RelativePos(c.Depth-contours[i-1].Depth), "Move down to the next contour")
+		}
+
+		if err := builder.DrawCircle(center, float32(c.Radius)); err != nil {
+			return fmt.Errorf("contour %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// revolveSpiral draws contours as one continuous line connecting them with spirals.
+func revolveSpiral(builder *gcb.GCodeBuilder, center gcb.BetterPoint[gcb.AbsolutePos], contours []Contour) error {
+	if len(contours) == 0 {
+		return nil
+	}
+
+	// the first circle starts as in DrawCircle
+	angle := math.Pi / 2
+	c := geom.Pt(float64(center.X), float64(center.Y))
+	pointAt := func(angle, r float64) gcb.BetterPoint[gcb.AbsolutePos] {
+		return gcb.BetterPt(gcb.AbsolutePos(c.X+r*math.Cos(angle)), gcb.AbsolutePos(c.Y+r*math.Sin(angle)))
+	}
+
+	if err := builder.Move(pointAt(angle, contours[0].Radius)); err != nil {
+		return fmt.Errorf("contour 0: %w", err)
+	}
+
+	if err := builder.BeginContinousLine(); err != nil {
+		return err
+	}
+
+	builder.BeginPass()
+	for i, contour := range contours {
+		if err := builder.DrawArc(builder.Current(), builder.Current(), center, true); err != nil {
+			return fmt.Errorf("contour %d: %w", i, err)
+		}
+
+		if i == len(contours)-1 {
+			break
+		}
+
+		// 1.0: spiral to the next contour (clockwise as DrawCircle)
+		builder.BeginPass()
+		next := contours[i+1]
+		r := math.Max(contour.Radius, next.Radius)
+		arc := geom.Arc{Center: c, RX: r, RY: r, Start: angle, Delta: -math.Pi / 2}
+		n := len(arc.Flatten(builder.Tolerance())) - 1
+
+		spiral := make(toolpath.Polyline, 0, n+1)
+		for j := 0; j <= n; j++ {
+			t := float64(j) / float64(n)
+			r := contour.Radius + t*(next.Radius-contour.Radius)
+			a := angle - t*math.Pi/2
+			spiral = append(spiral, geom.Pt(c.X+r*math.Cos(a), c.Y+r*math.Sin(a)))
+		}
+
+		length := spiral.Length()
+		for j := 1; j < len(spiral); j++ {
+			dz := -1 * gcb.RelativePos((next.Depth-contour.Depth)*spiral[j].Dist(spiral[j-1])/length)
+			if err := builder.Ramp(gcb.BetterPt(gcb.AbsolutePos(spiral[j].X), gcb.AbsolutePos(spiral[j].Y)), dz); err != nil {
+				return fmt.Errorf("spiral to contour %d: %w", i+1, err)
+			}
+		}
+
+		angle -= math.Pi / 2
+	}
+
+	return builder.EndContinousLine()
+}
@@ -0,0 +1,291 @@
+package spiffy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/kpango/glg"
+)
+
+// errUnknownViewport is returned by lengthAttrs if a percentage can not be resolved.
+// Elements using such lengths are skipped.
+var errUnknownViewport = errors.New("percentage length without viewBox nor width/height of the <svg>")
+
+// percentOf tells which size of the viewport a percentage length of an attribute refers to:
+// width (w), height (h) or normalized diagonal (d).
+// refer: https://www.w3.org/TR/SVG2/coords.html#Units
+var percentOf = map[string]byte{
+	"x": 'w', "cx": 'w', "x1": 'w', "x2": 'w', "width": 'w', "rx": 'w',
+	"y": 'h', "cy": 'h', "y1": 'h', "y2": 'h', "height": 'h', "ry": 'h',
+	"r": 'd',
+}
+
+// lengthAttrs parses several length attributes of e at once.
+// Lengths are returned in user units (see parseLength). auto is the same as an unset attribute (0)
+// and percentages are resolved against the viewport of the nearest <svg> (see viewportSize).
+func lengthAttrs(e *element, dpi float64, names ...string) ([]float64, error) {
+	result := make([]float64, len(names))
+	for i, name := range names {
+		value := strings.TrimSpace(e.Attr(name))
+		if value == "auto" {
+			continue
+		}
+
+		v, unit, err := splitLength(value)
+		if value == "" || err != nil || unit != "%" {
+			v, err = parseLength(value, dpi)
+		} else {
+			v, err = resolvePercentage(e, dpi, name, v)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s of %s: %w", name, e.ID(), err)
+		}
+
+		result[i] = v
+	}
+
+	return result, nil
+}
+
+// isSet returns true if the length attribute is set (not empty nor auto).
+func isSet(e *element, name string) bool {
+	value := strings.TrimSpace(e.Attr(name))
+	return value != "" && value != "auto"
+}
+
+// resolvePercentage returns percent of the viewport's size of the attribute (see percentOf).
+func resolvePercentage(e *element, dpi float64, name string, percent float64) (float64, error) {
+	width, height, ok := viewportSize(e, dpi)
+	if !ok {
+		return 0, errUnknownViewport
+	}
+
+	switch percentOf[name] {
+	case 'w':
+		return percent / 100 * width, nil
+	case 'h':
+		return percent / 100 * height, nil
+	case 'd':
+		return percent / 100 * math.Sqrt((width*width+height*height)/2), nil
+	}
+
+	return 0, errors.New("percentage is not supported")
+}
+
+// viewportSize returns size (in user units) of the viewport of the nearest <svg> ancestor of e:
+// its viewBox or (without viewBox) its width and height.
+func viewportSize(e *element, dpi float64) (width, height float64, ok bool) {
+	for e != nil && e.Name != "svg" {
+		e = e.Parent
+	}
+
+	if e == nil {
+		return 0, 0, false
+	}
+
+	if vb, ok, err := parseViewBox(e.Attr("viewBox")); err == nil && ok {
+		return vb.Width, vb.Height, true
+	}
+
+	if strings.HasSuffix(e.Attr("width"), "%") || strings.HasSuffix(e.Attr("height"), "%") {
+		return 0, 0, false
+	}
+
+	width, errW := parseLength(e.Attr("width"), dpi)
+	height, errH := parseLength(e.Attr("height"), dpi)
+
+	return width, height, errW == nil && errH == nil && width > 0 && height > 0
+}
+
+// shapeToPath converts a basic SVG shape (or path) to path data.
+// dpi is used to convert lengths with units to user units.
+// ok is false if e is not a drawable shape.
+// refer: https://www.w3.org/TR/SVG2/shapes.html
+func shapeToPath(e *element, dpi float64) (data PathData, ok bool, err error) {
+	switch e.Name {
+	case "path":
+		data, err = ParsePathData(e.Attr("d"))
+	case "rect":
+		data, err = rectToPath(e, dpi)
+	case "circle":
+		data, err = circleToPath(e, dpi)
+	case "ellipse":
+		data, err = ellipseToPath(e, dpi)
+	case "line":
+		data, err = lineToPath(e, dpi)
+	case "polyline":
+		data, err = polyToPath(e, false)
+	case "polygon":
+		data, err = polyToPath(e, true)
+	default:
+		return nil, false, nil
+	}
+
+	switch {
+	case errors.Is(err, errUnknownViewport):
+		glg.Warnf("<%s> %s: %v, skipping", e.Name, e.ID(), err)
+		return nil, true, nil
+	case err != nil:
+		return nil, true, fmt.Errorf("converting <%s> %s to path: %w", e.Name, e.ID(), err)
+	}
+
+	return data, true, nil
+}
+
+func rectToPath(e *element, dpi float64) (PathData, error) {
+	v, err := lengthAttrs(e, dpi, "x", "y", "width", "height")
+	if err != nil {
+		return nil, err
+	}
+
+	x, y, w, h := v[0], v[1], v[2], v[3]
+	if w <= 0 || h <= 0 {
+		glg.Warnf("rect %s has non-positive size, skipping", e.ID())
+		return nil, nil
+	}
+
+	// 1.0: find corner radii. If only one is set, the other one is the same.
+	r, err := lengthAttrs(e, dpi, "rx", "ry")
+	if err != nil {
+		return nil, err
+	}
+
+	rx, ry := r[0], r[1]
+	switch {
+	case !isSet(e, "rx") && isSet(e, "ry"):
+		rx = ry
+	case !isSet(e, "ry") && isSet(e, "rx"):
+		ry = rx
+	}
+
+	rx = math.Min(math.Max(rx, 0), w/2)
+	ry = math.Min(math.Max(ry, 0), h/2)
+
+	// 2.0: sharp corners
+	if rx == 0 || ry == 0 {
+		return PathData{
+			{Type: PathMoveToAbs, Args: []float64{x, y}},
+			{Type: PathLineToHorizontalAbs, Args: []float64{x + w}},
+			{Type: PathLineToVerticalAbs, Args: []float64{y + h}},
+			{Type: PathLineToHorizontalAbs, Args: []float64{x}},
+			{Type: PathCloseAbs},
+		}, nil
+	}
+
+	// 3.0: rounded corners
+	return PathData{
+		{Type: PathMoveToAbs, Args: []float64{x + rx, y}},
+		{Type: PathLineToHorizontalAbs, Args: []float64{x + w - rx}},
+		{Type: PathEllipticalArcRel, Args: []float64{rx, ry, 0, 0, 1, rx, ry}},
+		{Type: PathLineToVerticalAbs, Args: []float64{y + h - ry}},
+		{Type: PathEllipticalArcRel, Args: []float64{rx, ry, 0, 0, 1, -rx, ry}},
+		{Type: PathLineToHorizontalAbs, Args: []float64{x + rx}},
+		{Type: PathEllipticalArcRel, Args: []float64{rx, ry, 0, 0, 1, -rx, -ry}},
+		{Type: PathLineToVerticalAbs, Args: []float64{y + ry}},
+		{Type: PathEllipticalArcRel, Args: []float64{rx, ry, 0, 0, 1, rx, -ry}},
+		{Type: PathCloseAbs},
+	}, nil
+}
+
+func circleToPath(e *element, dpi float64) (PathData, error) {
+	v, err := lengthAttrs(e, dpi, "cx", "cy", "r")
+	if err != nil {
+		return nil, err
+	}
+
+	if v[2] <= 0 {
+		glg.Warnf("circle %s has non-positive radius, skipping", e.ID())
+		return nil, nil
+	}
+
+	return ellipsePath(v[0], v[1], v[2], v[2]), nil
+}
+
+func ellipseToPath(e *element, dpi float64) (PathData, error) {
+	v, err := lengthAttrs(e, dpi, "cx", "cy", "rx", "ry")
+	if err != nil {
+		return nil, err
+	}
+
+	rx, ry := v[2], v[3]
+	switch {
+	case !isSet(e, "rx") && isSet(e, "ry"):
+		rx = ry
+	case !isSet(e, "ry") && isSet(e, "rx"):
+		ry = rx
+	}
+
+	if rx <= 0 || ry <= 0 {
+		glg.Warnf("ellipse %s has non-positive radius, skipping", e.ID())
+		return nil, nil
+	}
+
+	return ellipsePath(v[0], v[1], rx, ry), nil
+}
+
+// ellipsePath returns an ellipse made of 2 arcs.
+// It starts at (cx+rx, cy) and goes in the positive-angle direction (like SVG does).
+func ellipsePath(cx, cy, rx, ry float64) PathData {
+	return PathData{
+		{Type: PathMoveToAbs, Args: []float64{cx + rx, cy}},
+		{Type: PathEllipticalArcAbs, Args: []float64{rx, ry, 0, 0, 1, cx - rx, cy}},
+		{Type: PathEllipticalArcAbs, Args: []float64{rx, ry, 0, 0, 1, cx + rx, cy}},
+		{Type: PathCloseAbs},
+	}
+}
+
+func lineToPath(e *element, dpi float64) (PathData, error) {
+	v, err := lengthAttrs(e, dpi, "x1", "y1", "x2", "y2")
+	if err != nil {
+		return nil, err
+	}
+
+	return PathData{
+		{Type: PathMoveToAbs, Args: []float64{v[0], v[1]}},
+		{Type: PathLineToAbs, Args: []float64{v[2], v[3]}},
+	}, nil
+}
+
+// polyToPath converts polyline (or polygon if closed) to path.
+func polyToPath(e *element, closed bool) (PathData, error) {
+	l := newPathLexer(e.Attr("points"))
+
+	var coords []float64
+
+	for l.hasNumber() {
+		n, err := l.number()
+		if err != nil {
+			return nil, err
+		}
+
+		coords = append(coords, n)
+	}
+
+	if !l.eof() {
+		return nil, l.errorf(l.pos, "unexpected character %q in points", l.data[l.pos])
+	}
+
+	// odd number of coordinates is an error, but the spec says we should render what we can.
+	if len(coords)%2 != 0 {
+		glg.Warnf("%s %s has odd number of coordinates, ignoring the last one", e.Name, e.ID())
+		coords = coords[:len(coords)-1]
+	}
+
+	if len(coords) == 0 {
+		return nil, nil
+	}
+
+	result := PathData{{Type: PathMoveToAbs, Args: coords[0:2]}}
+	for i := 2; i < len(coords); i += 2 {
+		result = append(result, PathCommand{Type: PathLineToAbs, Args: coords[i : i+2]})
+	}
+
+	if closed {
+		result = append(result, PathCommand{Type: PathCloseAbs})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package spiffy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShapeToPath(t *testing.T) {
+	tests := []struct {
+		name string
+		svg  string
+		// want is the path data (see PathCommand.String) or empty if the shape is skipped.
+		want string
+	}{
+		{
+			"rect",
+			`<svg><rect x="1" y="2" width="10" height="5"/></svg>`,
+			"M 1 2; H 11; V 7; H 1; Z",
+		},
+		{
+			"rect with auto radius",
+			`<svg><rect width="10" height="5" rx="auto"/></svg>`,
+			"M 0 0; H 10; V 5; H 0; Z",
+		},
+		{
+			"auto radius is the other one",
+			`<svg><rect width="10" height="5" rx="auto" ry="1"/></svg>`,
+			"M 1 0; H 9; a 1 1 0 0 1 1 1; V 4; a 1 1 0 0 1 -1 1; H 1; a 1 1 0 0 1 -1 -1; V 1; a 1 1 0 0 1 1 -1; Z",
+		},
+		{
+			"percentages of the viewBox",
+			`<svg viewBox="0 0 200 100"><g><rect x="10%" y="10%" width="50%" height="50%"/></g></svg>`,
+			"M 20 10; H 120; V 60; H 20; Z",
+		},
+		{
+			"percentages of the size",
+			`<svg width="200" height="100"><line x1="0" y1="0" x2="100%" y2="100%"/></svg>`,
+			"M 0 0; L 200 100",
+		},
+		{
+			"percentage radius of the normalized diagonal",
+			`<svg viewBox="0 0 30 40"><circle r="10%"/></svg>`,
+			"M 3.5355339059327378 0; A 3.5355339059327378 3.5355339059327378 0 0 1 -3.5355339059327378 0; " +
+				"A 3.5355339059327378 3.5355339059327378 0 0 1 3.5355339059327378 0; Z",
+		},
+		{
+			"percentage without viewport is skipped",
+			`<svg width="100%"><rect width="50%" height="10"/></svg>`,
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := parseDocument([]byte(tt.svg))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			e := root
+			for len(e.Children) > 0 {
+				e = e.Children[0]
+			}
+
+			data, ok, err := shapeToPath(e, DefaultDPI)
+			if err != nil || !ok {
+				t.Fatalf("unexpected result %v, %v", ok, err)
+			}
+
+			commands := make([]string, len(data))
+			for i, c := range data {
+				commands[i] = c.String()
+			}
+
+			if got := strings.Join(commands, "; "); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,22 +1,26 @@
 package spiffy
 
 import (
-	"errors"
 	"fmt"
+	"os"
 
 	"github.com/gucio321/spiffy/pkg/gcb"
+	"github.com/gucio321/spiffy/pkg/geom"
+	"github.com/gucio321/spiffy/pkg/toolpath"
 	"github.com/gucio321/spiffy/pkg/workspace"
 	"github.com/kpango/glg"
-	"github.com/rustyoz/svg"
 )
 
 type Spiffy struct {
 	scale     float64
 	noComment bool
-	svg       *svg.Svg
+	doc       *element
 	repeat    struct {
 		nTimes   int
 		moveDown float64
+		strategy RepeatStrategy
+		// wall is a wall angle profile (see RepeatOffset)
+		wall WallProfile
 	}
 	depth struct {
 		workingDepth float64
@@ -24,12 +28,34 @@ type Spiffy struct {
 	}
 	workspace     *workspace.Workspace
 	workspaceName string
+	nativeArcs    bool
+	dpi           float64
+	tolerance     float64
+	optimize      bool
+	simplify      float64
+	collect       bool
+	absolute      bool
+	feeds         gcb.Feeds
+	dialect       gcb.Dialect
+	precision     int
+	jobName       string
+	// center is an axis of a revolved part (see Revolve)
+	center *geom.Point
+	// preamble, postamble are templates overriding workspace's ones
+	preamble, postamble string
+	size                struct {
+		width, height float64
+	}
 }
 
 func NewSpiffy() *Spiffy {
 	return &Spiffy{
 		workspaceName: gcb.DefaultWorkspace,
 		scale:         1.0,
+		dpi:           DefaultDPI,
+		tolerance:     gcb.DefaultTolerance,
+		dialect:       gcb.DefaultDialect,
+		precision:     gcb.DialectPrecision,
 	}
 }
 
@@ -60,105 +86,378 @@ func (s *Spiffy) NoComment() *Spiffy {
 	return s
 }
 
+// DPI sets how many px (SVG user units) are in one inch.
+// Default is 96 (as in CSS), but some programs use different values (e.g. 72 or 90).
+func (s *Spiffy) DPI(dpi float64) *Spiffy {
+	s.dpi = dpi
+	return s
+}
+
+// Size scales the document to the given physical size (in mm).
+// If one of the dimensions is 0, it is computed from the aspect ratio.
+func (s *Spiffy) Size(width, height float64) *Spiffy {
+	s.size.width = width
+	s.size.height = height
+
+	return s
+}
+
+// Tolerance sets max distance (in mm) between curves (beziers, arcs) and lines approximating them.
+// Tolerances smaller than gcb.MinTolerance are replaced by it (see GCodeBuilder.SetTolerance).
+func (s *Spiffy) Tolerance(tolerance float64) *Spiffy {
+	s.tolerance = max(tolerance, gcb.MinTolerance)
+	return s
+}
+
+// Simplify sets tolerance (in mm) of polyline simplification (Ramer–Douglas–Peucker).
+// Points closer than tolerance to the simplified line are removed.
+// With 0 (default) only zero-length and exactly collinear lines are merged.
+func (s *Spiffy) Simplify(tolerance float64) *Spiffy {
+	s.simplify = tolerance
+	return s
+}
+
+// CollectViolations makes GCode finish the job even if some points fall outside the workspace
+// and report all of them at once (see (*gcb.GCodeBuilder).CollectViolations).
+func (s *Spiffy) CollectViolations() *Spiffy {
+	s.collect = true
+	return s
+}
+
+// AbsolutePositioning makes GCode emit absolute (G90) coordinates (see (*gcb.GCodeBuilder).AbsolutePositioning).
+func (s *Spiffy) AbsolutePositioning() *Spiffy {
+	s.absolute = true
+	return s
+}
+
+// Feeds sets feed rates (in mm/min) of drawing, travel and plunge moves (see gcb.Feeds).
+func (s *Spiffy) Feeds(feeds gcb.Feeds) *Spiffy {
+	s.feeds = feeds
+	return s
+}
+
+// Dialect sets firmware dialect of the output (see gcb.Dialect).
+func (s *Spiffy) Dialect(d gcb.Dialect) *Spiffy {
+	s.dialect = d
+	return s
+}
+
+// Precision sets number of decimal places in the output (see (*gcb.GCodeBuilder).SetPrecision).
+func (s *Spiffy) Precision(precision int) *Spiffy {
+	s.precision = precision
+	return s
+}
+
+// JobName sets job name (available in preamble/postamble templates).
+func (s *Spiffy) JobName(name string) *Spiffy {
+	s.jobName = name
+	return s
+}
+
+// Preamble sets preamble and postamble templates (see gcb.TemplateData).
+// They override workspace's templates. Empty means default.
+func (s *Spiffy) Preamble(preamble, postamble string) *Spiffy {
+	s.preamble, s.postamble = preamble, postamble
+	return s
+}
+
+// Optimize enables reordering paths to minimize travel (tool up) moves.
+func (s *Spiffy) Optimize() *Spiffy {
+	s.optimize = true
+	return s
+}
+
+// NativeArcs enables G2/G3 arc moves even if the workspace does not enable them.
+func (s *Spiffy) NativeArcs() *Spiffy {
+	s.nativeArcs = true
+	return s
+}
+
 func (s *Spiffy) Repeat(nTimes int, moveDown float64) {
 	s.repeat.nTimes = nTimes
 	s.repeat.moveDown = moveDown
 }
 
+// RepeatStrategy describes how the head goes down between repeats (see Repeat).
+type RepeatStrategy int
+
+const (
+	// RepeatStepped moves down by moveDown at once before every repeat (default).
+	RepeatStepped RepeatStrategy = iota
+	// RepeatHelical moves down gradually while drawing, so that every path
+	// goes down by moveDown over its length in every repeat (no step marks).
+	// The paths are drawn once more at the final depth to flatten the slope.
+	RepeatHelical
+	// RepeatOffset moves down by moveDown before every repeat and offsets closed paths inward
+	// (holes in them outward) by moveDown / tan(wall angle), so that walls of the formed part
+	// are sloped (e.g. cones or pyramids).
+	// See WallAngle and WallProfile.
+	RepeatOffset
+)
+
+// Strategy sets a repeat strategy (see Repeat).
+func (s *Spiffy) Strategy(strategy RepeatStrategy) *Spiffy {
+	s.repeat.strategy = strategy
+	return s
+}
+
 // GCode returns single-purpose GCode for our project.
 func (s *Spiffy) GCode() (*gcb.GCodeBuilder, error) {
-	if s.workspace == nil {
-		var err error
-		s.workspace, err = workspace.Get(s.workspaceName)
-		if err != nil {
-			return nil, fmt.Errorf("unable to get workspace from name %s: %w", s.workspaceName, err)
-		}
+	builder, err := s.newBuilder()
+	if err != nil {
+		return nil, err
 	}
 
-	builder := gcb.NewGCodeBuilder(s.workspace)
-	if s.depth.workingDepth != 0 {
-		builder.SetDepth(gcb.RelativePos(s.depth.workingDepth))
+	// 1.0: draw paths
+	layer, err := s.Toolpath()
+	if err != nil {
+		return builder, err
 	}
 
-	// 1.0: draw paths
+	if s.optimize {
+		start := geom.Pt(float64(builder.Base().X), float64(builder.Base().Y))
+		before := toolpath.TravelLength(start, layer.Paths)
+		layer.Paths = toolpath.Optimize(start, layer.Paths)
+		after := toolpath.TravelLength(start, layer.Paths)
+
+		glg.Infof("Path order optimized: travel length %.2f mm -> %.2f mm", before, after)
+		builder.Commentf("Path order optimized: travel length %.2f mm -> %.2f mm", before, after)
+	}
+
+	if s.repeat.nTimes > 0 {
+		builder.BeginPass()
+	}
+
+	// commands of the pass (repeated by stepped strategies, see Repeat)
+	first := len(builder.Commands())
 	builder.Comment("Drawing PATHS from SVG")
-	parsedData, parsedErr := s.svg.ParseDrawingInstructions()
-	if parsedData == nil || parsedErr == nil {
-		return builder, errors.New("nil parsedData or parsedErr")
-	}
-
-	builder.BeginContinousLine()
-reading:
-	for {
-		select {
-		case cmd := <-parsedData:
-			if cmd == nil {
-				builder.EndContinousLine()
-				break reading
+	if err := toolpath.Emit(builder, layer.Paths...); err != nil {
+		return builder, err
+	}
+
+	// now repeat
+	if err := builder.Move(builder.Base()); err != nil {
+		return builder, fmt.Errorf("cant move to base position: %w", err)
+	}
+
+	if err := builder.Violations(); err != nil {
+		return builder, err
+	}
+
+	cmds := builder.Commands()[first:]
+	switch {
+	case s.repeat.nTimes > 0 && s.repeat.strategy == RepeatHelical:
+		for i := 0; i < s.repeat.nTimes; i++ {
+			builder.BeginPass()
+			builder.Commentf("Move down by %v while drawing the paths again.", s.repeat.moveDown)
+			if err := toolpath.EmitHelical(builder, -1*gcb.RelativePos(s.repeat.moveDown), s.tolerance, layer.Paths...); err != nil {
+				return builder, err
 			}
 
-			switch cmd.Kind {
-			case svg.MoveInstruction:
-				builder.EndContinousLine()
-				builder.Move(gcb.BetterPt[gcb.AbsolutePos](gcb.AbsolutePos(cmd.M[0]*s.scale), gcb.AbsolutePos(cmd.M[1]*s.scale)))
-				builder.BeginContinousLine()
-			case svg.CircleInstruction:
-				glg.Warn("Circle not implemented")
-			case svg.CurveInstruction:
-				builder.DrawBezier(
-					10,
-					builder.Current(),
-					gcb.BetterPt(gcb.AbsolutePos(cmd.CurvePoints.C1[0]*s.scale), gcb.AbsolutePos(cmd.CurvePoints.C1[1]*s.scale)),
-					gcb.BetterPt(gcb.AbsolutePos(cmd.CurvePoints.C2[0]*s.scale), gcb.AbsolutePos(cmd.CurvePoints.C2[1]*s.scale)),
-					gcb.BetterPt(gcb.AbsolutePos(cmd.CurvePoints.T[0]*s.scale), gcb.AbsolutePos(cmd.CurvePoints.T[1]*s.scale)),
-				)
-			case svg.LineInstruction:
-				builder.DrawLine(
-					builder.Current(),
-					gcb.BetterPt[gcb.AbsolutePos](gcb.AbsolutePos(cmd.M[0]*s.scale), gcb.AbsolutePos(cmd.M[1]*s.scale)),
-				)
-			case svg.CloseInstruction:
-				glg.Warn("Close not implemented")
-			case svg.PaintInstruction:
-				glg.Warn("Paint not implemented")
+			if err := builder.Move(builder.Base()); err != nil {
+				return builder, fmt.Errorf("cant move to base position: %w", err)
 			}
-		case err := <-parsedErr:
+		}
+
+		// flat pass at the final depth
+		builder.BeginPass()
+		builder.Comment("Draw the paths again at the final depth.")
+		if err := toolpath.Emit(builder, layer.Paths...); err != nil {
+			return builder, err
+		}
+
+		if err := builder.Move(builder.Base()); err != nil {
+			return builder, fmt.Errorf("cant move to base position: %w", err)
+		}
+	case s.repeat.nTimes > 0 && s.repeat.strategy == RepeatOffset:
+		for i := 1; i <= s.repeat.nTimes; i++ {
+			offset, err := s.offsetOf(i)
 			if err != nil {
-				panic(err)
 				return builder, err
 			}
+
+			paths := s.offsetPaths(layer.Paths, offset)
+			if len(paths) == 0 {
+				glg.Warnf("All paths vanished after %d repeats (offset %.2f mm)", i-1, offset)
+				break
+			}
+
+			builder.BeginPass()
+			builder.ShiftZ(-1*gcb.RelativePos(s.repeat.moveDown), fmt.Sprintf("Move down and draw the paths offset by %.3f mm.", offset))
+			if err := toolpath.Emit(builder, paths...); err != nil {
+				return builder, err
+			}
+
+			if err := builder.Move(builder.Base()); err != nil {
+				return builder, fmt.Errorf("cant move to base position: %w", err)
+			}
+		}
+	case s.repeat.nTimes > 0 && s.dialect.Supports(gcb.FeatureLoops):
+		if err := builder.BeginLoop(s.repeat.nTimes); err != nil {
+			return builder, err
+		}
+
+		builder.BeginPass()
+		builder.ShiftZ(-1*gcb.RelativePos(s.repeat.moveDown), "Move down and repeate the previous sequence.")
+		builder.PushCommand(cmds...)
+
+		if err := builder.EndLoop(); err != nil {
+			return builder, err
+		}
+	default:
+		for i := 0; i < s.repeat.nTimes; i++ {
+			builder.BeginPass()
+			builder.ShiftZ(-1*gcb.RelativePos(s.repeat.moveDown), "Move down and repeate the previous sequence.")
+			builder.PushCommand(cmds...)
 		}
 	}
 
-	// now repeat
-	builder.Move(gcb.BetterPt[gcb.AbsolutePos](gcb.AbsolutePos(gcb.BaseX)-gcb.AbsolutePos(s.workspace.MinX), gcb.AbsolutePos(gcb.BaseY)-gcb.AbsolutePos(s.workspace.MinY)))
-	cmds := builder.Commands()
-	for i := 0; i < s.repeat.nTimes; i++ {
-		builder.PushCommand(
-			gcb.Command{
-				LineComment: "Move down and repeate the previous sequence.",
-				Code:        gcb.G0,
-				Args: map[string]gcb.RelativePos{
-					"Z": -1 * gcb.RelativePos(s.repeat.moveDown),
-				},
-			})
+	// helical and offset repeats draw new points
+	if err := builder.Violations(); err != nil {
+		return builder, err
+	}
 
-		builder.PushCommand(cmds...)
+	return s.wrap(builder)
+}
+
+// newBuilder returns a builder configured for drawing the job's body (see wrap).
+func (s *Spiffy) newBuilder() (*gcb.GCodeBuilder, error) {
+	if s.workspace == nil {
+		var err error
+		s.workspace, err = workspace.Get(s.workspaceName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get workspace from name %s: %w", s.workspaceName, err)
+		}
+
+		if s.workspace == nil {
+			return nil, fmt.Errorf("workspace %s not found", s.workspaceName)
+		}
 	}
 
+	builder := gcb.NewGCodeBuilder(s.workspace)
+	builder.SetTolerance(s.tolerance)
+	builder.CollectViolations(s.collect)
+	builder.AbsolutePositioning(s.absolute)
+	builder.SetFeeds(s.feeds)
+	builder.SetDialect(s.dialect)
+	builder.SetPrecision(s.precision)
+	if s.nativeArcs {
+		builder.NativeArcs(true)
+	}
+
+	if s.depth.workingDepth != 0 {
+		builder.SetDepth(gcb.RelativePos(s.depth.workingDepth))
+	}
+
+	return builder, nil
+}
+
+// wrap returns a builder with preamble, postamble and depth calibration around the job's body.
+func (s *Spiffy) wrap(builder *gcb.GCodeBuilder) (*gcb.GCodeBuilder, error) {
 	newBuilder := gcb.NewGCodeBuilder(s.workspace)
+	newBuilder.AbsolutePositioning(s.absolute)
+	newBuilder.SetFeeds(s.feeds)
+	newBuilder.SetDialect(s.dialect)
+	newBuilder.SetPrecision(s.precision)
+	newBuilder.SetJobName(s.jobName)
+	if err := s.setTemplates(newBuilder); err != nil {
+		return builder, err
+	}
+
 	if s.depth.calibration != 0 {
-		newBuilder.PushCommand(
-			gcb.Command{
-				LineComment: "Calibrate the depth (move down)",
-				Code:        gcb.G0,
-				Args: map[string]gcb.RelativePos{
-					"Z": -1 * gcb.RelativePos(s.depth.calibration),
-				},
-			})
+		newBuilder.ShiftZ(-1*gcb.RelativePos(s.depth.calibration), "Calibrate the depth (move down)")
 	}
 
 	newBuilder.PushCommand(builder.Commands()...)
 
 	return newBuilder, nil
 }
+
+// setTemplates sets builder's preamble and postamble
+// (from the workspace's files, overridden by Preamble).
+func (s *Spiffy) setTemplates(builder *gcb.GCodeBuilder) error {
+	preamble, postamble := s.preamble, s.postamble
+	for _, t := range []struct {
+		text *string
+		path string
+	}{
+		{&preamble, s.workspace.Preamble},
+		{&postamble, s.workspace.Postamble},
+	} {
+		if *t.text != "" || t.path == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(t.path)
+		if err != nil {
+			return fmt.Errorf("cant read template of workspace %s: %w", s.workspace.Name, err)
+		}
+
+		*t.text = string(data)
+	}
+
+	if err := builder.SetPreamble(preamble); err != nil {
+		return err
+	}
+
+	return builder.SetPostamble(postamble)
+}
+
+// paths collects drawable elements (root and its children) and converts them to segments.
+// All segments are transformed to the document space (in millimeters).
+func (s *Spiffy) paths(root *element) ([][]Segment, error) {
+	var result [][]Segment
+
+	docTransform, err := documentTransform(s.doc, s.dpi, s.size.width, s.size.height)
+	if err != nil {
+		return nil, err
+	}
+
+	var walk func(e *element) error
+	walk = func(e *element) error {
+		switch e.Name {
+		case "svg", "g", "a", "switch":
+			for _, child := range e.Children {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		case "text", "use", "image":
+			glg.Warnf("<%s> %s is not supported (convert it to path with Inkscape)", e.Name, e.ID())
+			return nil
+		}
+
+		data, ok, err := shapeToPath(e, s.dpi)
+		if err != nil {
+			return err
+		}
+
+		// not drawable (e.g. <defs>, <title>, <metadata>)
+		if !ok || len(data) == 0 {
+			return nil
+		}
+
+		ctm, err := resolveTransform(e)
+		if err != nil {
+			return err
+		}
+
+		ctm = docTransform.Mul(ctm)
+		segments := data.Segments()
+		for i := range segments {
+			segments[i] = segments[i].Transform(ctm)
+		}
+
+		result = append(result, segments)
+
+		return nil
+	}
+
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,131 @@
+package spiffy
+
+import (
+	"github.com/gucio321/spiffy/pkg/geom"
+	"github.com/gucio321/spiffy/pkg/toolpath"
+)
+
+// Toolpath converts the SVG to the intermediate toolpath representation.
+// Coordinates are in millimeters (already scaled).
+// Curves are approximated within the tolerance (see Tolerance),
+// circular arcs are kept as arcs.
+// Zero-length and collinear lines are merged (see Simplify).
+func (s *Spiffy) Toolpath() (toolpath.Layer, error) {
+	return s.toolpathOf(s.doc)
+}
+
+// toolpathOf converts the element (and its children) to toolpaths (see Toolpath).
+func (s *Spiffy) toolpathOf(e *element) (toolpath.Layer, error) {
+	var result toolpath.Layer
+
+	paths, err := s.paths(e)
+	if err != nil {
+		return result, err
+	}
+
+	scale := geom.Scale(s.scale, s.scale)
+	for _, segments := range paths {
+		for i := range segments {
+			segments[i] = segments[i].Transform(scale)
+		}
+
+		for _, p := range segmentsToPaths(segments, s.tolerance) {
+			p = p.Simplify(s.simplify)
+			if !p.IsEmpty() {
+				result.Paths = append(result.Paths, p)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// segmentsToPaths converts segments to toolpaths (one per subpath).
+// Subpaths closed with Z are closed by a line to the subpath's start point.
+// Subpaths ending within tolerance from their start are considered closed too
+// and are sealed exactly at the start point.
+func segmentsToPaths(segments []Segment, tolerance float64) []toolpath.Path {
+	var result []toolpath.Path
+
+	var current *toolpath.Path
+
+	// finish adds the current path to result (sealing it if necessary).
+	finish := func() {
+		if current == nil || current.IsEmpty() {
+			return
+		}
+
+		if current.IsClosed(tolerance) {
+			current.Close()
+		}
+
+		result = append(result, *current)
+		current = nil
+	}
+
+	for _, seg := range segments {
+		// 1.0: start a new path if needed.
+		// (after closepath, the next subpath starts at the same point)
+		if seg.Kind == SegmentMove {
+			finish()
+			current = toolpath.NewPath(seg.End)
+
+			continue
+		}
+
+		if current == nil {
+			current = toolpath.NewPath(seg.Start)
+		}
+
+		// 2.0: add segment
+		switch seg.Kind {
+		case SegmentLine:
+			current.LineTo(seg.End)
+		case SegmentQuadratic:
+			points := geom.FlattenBezier(tolerance, seg.Start, seg.C1, seg.End)
+			for _, p := range points[1:] {
+				current.LineTo(p)
+			}
+		case SegmentCubic:
+			points := geom.FlattenBezier(tolerance, seg.Start, seg.C1, seg.C2, seg.End)
+			for _, p := range points[1:] {
+				current.LineTo(p)
+			}
+		case SegmentArc:
+			addArc(current, seg, tolerance)
+		case SegmentClose:
+			current.Close()
+			finish()
+		}
+	}
+
+	finish()
+
+	return result
+}
+
+// addArc adds an arc segment to the path. Circular arcs are kept as arcs
+// (so they can be drawn with G2/G3), elliptical ones are approximated with lines.
+func addArc(p *toolpath.Path, seg Segment, tolerance float64) {
+	arc, ok := seg.CenterArc()
+	if !ok {
+		// zero radius means straight line
+		if seg.Start != seg.End {
+			p.LineTo(seg.End)
+		}
+
+		return
+	}
+
+	if arc.IsCircular() {
+		p.ArcTo(seg.End, arc.Center, arc.Clockwise())
+		return
+	}
+
+	points := arc.Flatten(tolerance)
+	for _, pt := range points[1 : len(points)-1] {
+		p.LineTo(pt)
+	}
+
+	p.LineTo(seg.End)
+}
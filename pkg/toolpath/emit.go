@@ -0,0 +1,124 @@
+package toolpath
+
+import (
+	"fmt"
+
+	"github.com/gucio321/spiffy/pkg/gcb"
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+// absPos converts point to the builder's coordinates.
+func absPos(p geom.Point) gcb.BetterPoint[gcb.AbsolutePos] {
+	return gcb.BetterPt(gcb.AbsolutePos(p.X), gcb.AbsolutePos(p.Y))
+}
+
+// Emit draws paths with the builder. For each path, tool moves to its start,
+// goes down, follows the path and goes up.
+// Arcs are drawn with (*gcb.GCodeBuilder).DrawArc, so they may become native G2/G3 moves.
+func Emit(b *gcb.GCodeBuilder, paths ...Path) error {
+	for i, p := range paths {
+		if p.IsEmpty() {
+			continue
+		}
+
+		if err := emitPath(b, p); err != nil {
+			return fmt.Errorf("drawing path %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func emitPath(b *gcb.GCodeBuilder, p Path) error {
+	// 1.0: go to start
+	if err := b.Move(absPos(p.Start)); err != nil {
+		return err
+	}
+
+	if err := b.BeginContinousLine(); err != nil {
+		return err
+	}
+
+	// 2.0: draw segments. Subsequent lines are drawn at once.
+	var lines []gcb.BetterPoint[gcb.AbsolutePos]
+
+	flushLines := func() error {
+		if len(lines) == 0 {
+			return nil
+		}
+
+		err := b.DrawLines(append([]gcb.BetterPoint[gcb.AbsolutePos]{b.Current()}, lines...)...)
+		lines = lines[:0]
+
+		return err
+	}
+
+	for _, seg := range p.Segments {
+		if seg.Arc == nil {
+			lines = append(lines, absPos(seg.End))
+			continue
+		}
+
+		if err := flushLines(); err != nil {
+			return err
+		}
+
+		if err := b.DrawArc(b.Current(), absPos(seg.End), absPos(seg.Arc.Center), seg.Arc.Clockwise); err != nil {
+			return err
+		}
+	}
+
+	if err := flushLines(); err != nil {
+		return err
+	}
+
+	// 3.0: go up
+	return b.EndContinousLine()
+}
+
+// EmitHelical draws paths like Emit, but the head moves by dz along Z axis gradually:
+// every path goes down by the whole dz over its length (e.g. for incremental forming).
+// Paths but the last one go back up by dz after drawing, so that all of them start at the same height
+// and closed paths drawn again by the next EmitHelical start where they ended.
+// Paths are approximated with lines (see Path.Polyline).
+func EmitHelical(b *gcb.GCodeBuilder, dz gcb.RelativePos, tolerance float64, paths ...Path) error {
+	// 1.0: skip paths which can't be drawn
+	polylines := make([]Polyline, 0, len(paths))
+	for _, p := range paths {
+		if polyline := p.Polyline(tolerance); len(polyline) > 1 && polyline.Length() > 0 {
+			polylines = append(polylines, polyline)
+		}
+	}
+
+	// 2.0: draw
+	for i, polyline := range polylines {
+		if err := b.Move(absPos(polyline[0])); err != nil {
+			return fmt.Errorf("drawing path %d: %w", i, err)
+		}
+
+		if err := b.Down(); err != nil {
+			return err
+		}
+
+		total := polyline.Length()
+		for j := 1; j < len(polyline); j++ {
+			step := dz * gcb.RelativePos(polyline[j].Dist(polyline[j-1])/total)
+			if err := b.Ramp(absPos(polyline[j]), step); err != nil {
+				return fmt.Errorf("drawing path %d: %w", i, err)
+			}
+		}
+
+		if err := b.Up(); err != nil {
+			return err
+		}
+
+		// 2.1: go back to the height of the first path
+		if i < len(polylines)-1 {
+			if err := b.Ramp(b.Current(), -dz); err != nil {
+				return fmt.Errorf("drawing path %d: %w", i, err)
+			}
+		}
+	}
+
+	return nil
+}
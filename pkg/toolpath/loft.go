@@ -0,0 +1,126 @@
+package toolpath
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+// Loft returns n+1 closed polylines morphing from top (first) to bottom (last).
+// Both outlines are resampled to the same points (vertices of both of them at the same
+// relative length), then the points are interpolated linearly.
+// bottom is reversed and rotated if needed, so that both outlines start in the same direction
+// and go the same way around.
+func Loft(top, bottom Polyline, n int) []Polyline {
+	top, bottom = closed(top), closed(bottom)
+
+	// 1.0: align bottom with top
+	if top.Area()*bottom.Area() < 0 {
+		bottom = bottom.Reverse()
+	}
+
+	bottom = bottom.rotateTo(top[0].Sub(top.BBox().Center()), bottom.BBox().Center())
+
+	// 2.0: resample at vertices of both outlines
+	params := append(top.params(), bottom.params()...)
+	sort.Float64s(params)
+
+	unique := params[:0]
+	for _, t := range params {
+		if len(unique) == 0 || t-unique[len(unique)-1] > 1e-9 {
+			unique = append(unique, t)
+		}
+	}
+
+	a, b := top.resample(unique), bottom.resample(unique)
+
+	// 3.0: interpolate
+	result := make([]Polyline, n+1)
+	for i := range result {
+		t := 0.0
+		if n > 0 {
+			t = float64(i) / float64(n)
+		}
+
+		result[i] = make(Polyline, len(a))
+		for j := range a {
+			result[i][j] = a[j].Add(b[j].Sub(a[j]).Mul(t))
+		}
+
+		// resampling at the end may be off by a rounding error
+		result[i][len(a)-1] = result[i][0]
+	}
+
+	return result
+}
+
+// closed returns p ending at its start point.
+func closed(p Polyline) Polyline {
+	if len(p) > 0 && p[0] != p[len(p)-1] {
+		return append(p[:len(p):len(p)], p[0])
+	}
+
+	return p
+}
+
+// params returns relative length (0 to 1) of the polyline at its vertices.
+func (p Polyline) params() []float64 {
+	total := p.Length()
+	result := make([]float64, len(p))
+	length := 0.0
+	for i := 1; i < len(p); i++ {
+		length += p[i].Dist(p[i-1])
+		result[i] = length / total
+	}
+
+	return result
+}
+
+// resample returns points of the polyline at the relative lengths (sorted, from 0 to 1).
+func (p Polyline) resample(params []float64) Polyline {
+	total := p.Length()
+	result := make(Polyline, 0, len(params))
+
+	i, start := 1, 0.0
+	for _, t := range params {
+		length := t * total
+		for i < len(p)-1 && start+p[i].Dist(p[i-1]) < length {
+			start += p[i].Dist(p[i-1])
+			i++
+		}
+
+		segment := p[i].Dist(p[i-1])
+		if segment == 0 {
+			result = append(result, p[i])
+			continue
+		}
+
+		k := math.Min(1, math.Max(0, (length-start)/segment))
+		result = append(result, p[i-1].Add(p[i].Sub(p[i-1]).Mul(k)))
+	}
+
+	return result
+}
+
+// rotateTo returns the closed polyline starting at the vertex which direction from center
+// is the closest to dir.
+func (p Polyline) rotateTo(dir, center geom.Point) Polyline {
+	best, bestCos := 0, math.Inf(-1)
+	for i, pt := range p[:len(p)-1] {
+		v := pt.Sub(center)
+		if v.Len() == 0 || dir.Len() == 0 {
+			continue
+		}
+
+		if cos := (v.X*dir.X + v.Y*dir.Y) / (v.Len() * dir.Len()); cos > bestCos {
+			best, bestCos = i, cos
+		}
+	}
+
+	result := make(Polyline, 0, len(p))
+	result = append(result, p[best:len(p)-1]...)
+	result = append(result, p[:best+1]...)
+
+	return result
+}
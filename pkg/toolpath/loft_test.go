@@ -0,0 +1,99 @@
+package toolpath
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+func square(x0, y0, x1, y1 float64) Polyline {
+	return Polyline{geom.Pt(x0, y0), geom.Pt(x1, y0), geom.Pt(x1, y1), geom.Pt(x0, y1), geom.Pt(x0, y0)}
+}
+
+// distToPolyline returns distance of pt from the nearest segment of p.
+func distToPolyline(p Polyline, pt geom.Point) float64 {
+	result := math.Inf(1)
+	for i := 1; i < len(p); i++ {
+		a, b := p[i-1], p[i]
+		ab := b.Sub(a)
+		t := 0.0
+		if l := ab.X*ab.X + ab.Y*ab.Y; l > 0 {
+			t = math.Max(0, math.Min(1, ((pt.X-a.X)*ab.X+(pt.Y-a.Y)*ab.Y)/l))
+		}
+
+		result = math.Min(result, pt.Dist(a.Add(ab.Mul(t))))
+	}
+
+	return result
+}
+
+// sameShape returns true if got goes through all vertices of want and only along want.
+func sameShape(got, want Polyline) bool {
+	for _, v := range want {
+		if distToPolyline(got, v) > 1e-9 {
+			return false
+		}
+	}
+
+	for _, v := range got {
+		if distToPolyline(want, v) > 1e-9 {
+			return false
+		}
+	}
+
+	return math.Abs(math.Abs(got.Area())-math.Abs(want.Area())) < 1e-9
+}
+
+func TestLoft(t *testing.T) {
+	triangle := Polyline{geom.Pt(0, 0), geom.Pt(10, 0), geom.Pt(5, 8), geom.Pt(0, 0)}
+
+	tests := []struct {
+		name        string
+		top, bottom Polyline
+		n           int
+	}{
+		{"smaller square", square(0, 0, 10, 10), square(2, 2, 8, 8), 4},
+		{"reversed square", square(0, 0, 10, 10), square(2, 2, 8, 8).Reverse(), 3},
+		{"square to triangle", square(0, 0, 10, 10), triangle, 5},
+		{"open outlines are closed", square(0, 0, 10, 10)[:4], triangle[:3], 2},
+		{"single step", square(0, 0, 10, 10), square(1, 1, 9, 9), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Loft(tt.top, tt.bottom, tt.n)
+			if len(got) != tt.n+1 {
+				t.Fatalf("got %d polylines, want %d", len(got), tt.n+1)
+			}
+
+			for i, p := range got {
+				if len(p) != len(got[0]) || p[0] != p[len(p)-1] {
+					t.Errorf("polyline %d is not closed or has a different number of points: %v", i, p)
+				}
+			}
+
+			if !sameShape(got[0], closed(tt.top)) {
+				t.Errorf("first polyline %v is not the top outline %v", got[0], tt.top)
+			}
+
+			if !sameShape(got[tt.n], closed(tt.bottom)) {
+				t.Errorf("last polyline %v is not the bottom outline %v", got[tt.n], tt.bottom)
+			}
+
+			// outlines go the same way around
+			for i := 1; i < len(got); i++ {
+				if got[i].Area()*got[0].Area() <= 0 {
+					t.Errorf("polyline %d changed orientation", i)
+				}
+			}
+		})
+	}
+}
+
+func TestLoft_NoSteps(t *testing.T) {
+	got := Loft(square(0, 0, 10, 10), square(2, 2, 8, 8), 0)
+	if len(got) != 1 || !sameShape(got[0], square(0, 0, 10, 10)) {
+		t.Errorf("expected only the top outline, got %v", got)
+	}
+}
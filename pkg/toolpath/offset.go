@@ -0,0 +1,197 @@
+package toolpath
+
+import (
+	"math"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+// MiterLimit is the longest miter (in offset distances) of an offset corner.
+// Sharper corners are beveled (see Polyline.Offset).
+const MiterLimit = 2
+
+// offsetLine is a line of an offset edge: it goes through Point in Dir (unit) direction.
+// Corner is the start of the original edge.
+type offsetLine struct {
+	Point, Dir, Corner geom.Point
+}
+
+// project returns projection of p on l.
+func (l offsetLine) project(p geom.Point) geom.Point {
+	d := p.Sub(l.Point)
+	return l.Point.Add(l.Dir.Mul(d.X*l.Dir.X + d.Y*l.Dir.Y))
+}
+
+// intersect returns intersection of l and other (false if they are parallel).
+func (l offsetLine) intersect(other offsetLine) (geom.Point, bool) {
+	cross := l.Dir.X*other.Dir.Y - l.Dir.Y*other.Dir.X
+	if math.Abs(cross) < 1e-9 {
+		return geom.Point{}, false
+	}
+
+	d := other.Point.Sub(l.Point)
+	t := (d.X*other.Dir.Y - d.Y*other.Dir.X) / cross
+
+	return l.Point.Add(l.Dir.Mul(t)), true
+}
+
+// Area returns signed area of the closed polyline (shoelace formula).
+// Sign depends on the orientation of points.
+func (p Polyline) Area() (result float64) {
+	for i := range p {
+		a, b := p[i], p[(i+1)%len(p)]
+		result += a.X*b.Y - b.X*a.Y
+	}
+
+	return result / 2
+}
+
+// Contains returns true if pt is inside the closed polyline (even-odd rule).
+func (p Polyline) Contains(pt geom.Point) (result bool) {
+	for i := range p {
+		a, b := p[i], p[(i+1)%len(p)]
+		if (a.Y > pt.Y) != (b.Y > pt.Y) && pt.X < a.X+(pt.Y-a.Y)/(b.Y-a.Y)*(b.X-a.X) {
+			result = !result
+		}
+	}
+
+	return result
+}
+
+// Nesting returns how many other closed paths contain every path
+// (0 for outer paths, 1 for holes in them, 2 for islands in the holes and so on).
+// A path is inside another one if its start point is. Open paths are never inside nor contain others.
+func Nesting(paths []Path, tolerance float64) []int {
+	polylines := make([]Polyline, len(paths))
+	for i, p := range paths {
+		if p.IsClosed(0) {
+			polylines[i] = p.Polyline(tolerance)
+		}
+	}
+
+	result := make([]int, len(paths))
+	for i, p := range paths {
+		if polylines[i] == nil {
+			continue
+		}
+
+		for j, other := range polylines {
+			if i != j && other != nil && other.Contains(p.Start) {
+				result[i]++
+			}
+		}
+	}
+
+	return result
+}
+
+// Offset returns the closed polyline p offset by distance (inward if positive, outward if negative).
+// Corners are mitered (corners turning away from the offset are beveled
+// if the miter is longer than MiterLimit distances).
+// Edges which vanish in the offset polygon are removed.
+// Returns nil if the whole polygon vanishes.
+// NOTE: parts of the polygon which would become separate polygons are not split.
+func (p Polyline) Offset(distance float64) Polyline {
+	// 1.0: prepare points (without the closing one)
+	points := p.Simplify(0)
+	if len(points) > 1 && points[0] == points[len(points)-1] {
+		points = points[:len(points)-1]
+	}
+
+	area := points.Area()
+	if len(points) < 3 || area == 0 {
+		return nil
+	}
+
+	if distance == 0 {
+		return append(points, points[0])
+	}
+
+	// inside is on the left of edges if area is positive
+	side := 1.0
+	if area < 0 {
+		side = -1
+	}
+
+	// 2.0: offset lines of edges
+	lines := make([]offsetLine, 0, len(points))
+	for i, a := range points {
+		b := points[(i+1)%len(points)]
+		dir := b.Sub(a).Mul(1 / b.Dist(a))
+		normal := geom.Pt(-dir.Y, dir.X).Mul(side * distance)
+		lines = append(lines, offsetLine{Point: a.Add(normal), Dir: dir, Corner: a})
+	}
+
+	// 3.0: remove lines of vanished edges (these, which changed direction) until there are none
+	for {
+		if len(lines) < 3 {
+			return nil
+		}
+
+		vertices := make([]geom.Point, len(lines))
+		for i, line := range lines {
+			prev := lines[(i+len(lines)-1)%len(lines)]
+			if v, ok := prev.intersect(line); ok {
+				vertices[i] = v
+			} else {
+				vertices[i] = line.Point
+			}
+		}
+
+		vanished := -1
+		for i, line := range lines {
+			edge := vertices[(i+1)%len(vertices)].Sub(vertices[i])
+			if edge.X*line.Dir.X+edge.Y*line.Dir.Y < 0 {
+				vanished = i
+				break
+			}
+		}
+
+		if vanished < 0 {
+			break
+		}
+
+		lines = append(lines[:vanished], lines[vanished+1:]...)
+	}
+
+	// 4.0: corners
+	result := make(Polyline, 0, len(lines)+1)
+	for i, line := range lines {
+		prev := lines[(i+len(lines)-1)%len(lines)]
+		v, ok := prev.intersect(line)
+
+		// miters of corners turning towards the offset are exact
+		exact := (prev.Dir.X*line.Dir.Y-prev.Dir.Y*line.Dir.X)*side*distance > 0
+		if !ok || !exact && v.Dist(line.Corner) > MiterLimit*math.Abs(distance) {
+			// bevel
+			result = append(result, prev.project(line.Corner), line.project(line.Corner))
+
+			continue
+		}
+
+		result = append(result, v)
+	}
+
+	// 5.0: the polygon vanishes if it turned inside out
+	if result.Area()*area <= 0 {
+		return nil
+	}
+
+	return append(result, result[0])
+}
+
+// Offset returns the closed path offset by distance (see Polyline.Offset).
+// Arcs are approximated with lines within tolerance. Open paths are returned untouched.
+// ok is false if the path vanishes.
+func (p Path) Offset(distance, tolerance float64) (result Path, ok bool) {
+	if !p.IsClosed(0) || distance == 0 {
+		return p, true
+	}
+
+	polyline := p.Polyline(tolerance).Offset(distance)
+	if polyline == nil {
+		return Path{}, false
+	}
+
+	return PathFromPolyline(polyline), true
+}
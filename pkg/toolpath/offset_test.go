@@ -0,0 +1,105 @@
+package toolpath
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+// concave is an L-shaped polygon (area 64) with a reflex corner at (4, 4).
+var concave = Polyline{
+	geom.Pt(0, 0), geom.Pt(10, 0), geom.Pt(10, 4), geom.Pt(4, 4), geom.Pt(4, 10), geom.Pt(0, 10), geom.Pt(0, 0),
+}
+
+func TestPolyline_Offset(t *testing.T) {
+	tests := []struct {
+		name     string
+		polyline Polyline
+		distance float64
+		// area is 0 if the polygon vanishes
+		area     float64
+		vertices []geom.Point
+	}{
+		{"square inward", square(0, 0, 10, 10), 1, 64, []geom.Point{{X: 1, Y: 1}, {X: 9, Y: 1}, {X: 9, Y: 9}, {X: 1, Y: 9}}},
+		{"clockwise square inward", square(0, 0, 10, 10).Reverse(), 1, 64, []geom.Point{{X: 1, Y: 1}, {X: 9, Y: 9}}},
+		{"square outward", square(0, 0, 10, 10), -1, 144, []geom.Point{{X: -1, Y: -1}, {X: 11, Y: 11}}},
+		{"square not offset", square(0, 0, 10, 10), 0, 100, nil},
+		{"square vanishes", square(0, 0, 10, 10), 5, 0, nil},
+		{"square vanishes over", square(0, 0, 10, 10), 6, 0, nil},
+		{"concave inward", concave, 1, 28, []geom.Point{{X: 1, Y: 1}, {X: 9, Y: 3}, {X: 3, Y: 3}, {X: 1, Y: 9}}},
+		{"concave outward", concave, -1, 108, []geom.Point{{X: -1, Y: -1}, {X: 11, Y: 5}, {X: 5, Y: 5}, {X: 5, Y: 11}}},
+		{"concave arm vanishes", concave, 2.5, 0, nil},
+		{"degenerate", Polyline{geom.Pt(0, 0), geom.Pt(10, 0), geom.Pt(0, 0)}, 1, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.polyline.Offset(tt.distance)
+			if tt.area == 0 {
+				if got != nil {
+					t.Fatalf("expected the polygon to vanish, got %v", got)
+				}
+
+				return
+			}
+
+			if len(got) < 4 || got[0] != got[len(got)-1] {
+				t.Fatalf("expected a closed polygon, got %v", got)
+			}
+
+			if area := math.Abs(got.Area()); math.Abs(area-tt.area) > 1e-9 {
+				t.Errorf("area is %v, want %v (%v)", area, tt.area, got)
+			}
+
+		vertices:
+			for _, v := range tt.vertices {
+				for _, p := range got {
+					if p.Dist(v) < 1e-9 {
+						continue vertices
+					}
+				}
+
+				t.Errorf("vertex %v not found in %v", v, got)
+			}
+		})
+	}
+}
+
+func TestPolyline_Contains(t *testing.T) {
+	tests := []struct {
+		pt   geom.Point
+		want bool
+	}{
+		{geom.Pt(2, 2), true},
+		{geom.Pt(8, 2), true},
+		{geom.Pt(2, 8), true},
+		{geom.Pt(8, 8), false},
+		{geom.Pt(-1, 5), false},
+		{geom.Pt(11, 2), false},
+	}
+
+	for _, tt := range tests {
+		if got := concave.Contains(tt.pt); got != tt.want {
+			t.Errorf("Contains(%v) = %v, want %v", tt.pt, got, tt.want)
+		}
+	}
+}
+
+func TestNesting(t *testing.T) {
+	paths := []Path{
+		PathFromPolyline(square(0, 0, 10, 10)),
+		PathFromPolyline(square(2, 2, 8, 8).Reverse()),
+		PathFromPolyline(square(4, 4, 6, 6)),
+		PathFromPolyline(square(20, 0, 30, 10)),
+		PathFromPolyline(Polyline{geom.Pt(3, 3), geom.Pt(7, 7)}),
+	}
+
+	want := []int{0, 1, 2, 0, 0}
+	got := Nesting(paths, 0.01)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("path %d: nesting is %d, want %d", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package toolpath
+
+import "github.com/gucio321/spiffy/pkg/geom"
+
+// maxOptimizePasses limits number of 2-opt passes.
+const maxOptimizePasses = 100
+
+// TravelLength returns length of travel moves (tool up) needed to draw paths in the given order,
+// starting at start and returning there at the end.
+func TravelLength(start geom.Point, paths []Path) (result float64) {
+	current := start
+	for _, p := range paths {
+		if p.IsEmpty() {
+			continue
+		}
+
+		result += current.Dist(p.Start)
+		current = p.End()
+	}
+
+	return result + current.Dist(start)
+}
+
+// Optimize reorders paths to minimize travel length (see TravelLength).
+// Open paths may be reversed, closed ones are kept as they are.
+// It uses nearest neighbour heuristic improved with 2-opt.
+// Empty paths are removed.
+func Optimize(start geom.Point, paths []Path) []Path {
+	result := nearestNeighbour(start, paths)
+	twoOpt(start, result)
+
+	return result
+}
+
+// nearestNeighbour always picks the closest path (in any direction if open).
+func nearestNeighbour(start geom.Point, paths []Path) []Path {
+	remaining := make([]Path, 0, len(paths))
+	for _, p := range paths {
+		if !p.IsEmpty() {
+			remaining = append(remaining, p)
+		}
+	}
+
+	result := make([]Path, 0, len(remaining))
+	current := start
+
+	for len(remaining) > 0 {
+		best, bestDist, reverse := 0, current.Dist(remaining[0].Start), false
+		for i, p := range remaining {
+			if d := current.Dist(p.Start); d < bestDist {
+				best, bestDist, reverse = i, d, false
+			}
+
+			if d := current.Dist(p.End()); d < bestDist {
+				best, bestDist, reverse = i, d, true
+			}
+		}
+
+		p := remaining[best]
+		if reverse {
+			p = p.Reverse()
+		}
+
+		result = append(result, p)
+		current = p.End()
+
+		remaining[best] = remaining[len(remaining)-1]
+		remaining = remaining[:len(remaining)-1]
+	}
+
+	return result
+}
+
+// twoOpt improves order of paths in place by reversing sub-sequences.
+// Reversing paths i..j reverses their order and direction, so only two travel moves change:
+// (end of i-1 -> start of i) and (end of j -> start of j+1) become
+// (end of i-1 -> end of j) and (start of i -> start of j+1).
+// Closed paths start and end in the same point, so their direction doesn't matter.
+func twoOpt(start geom.Point, paths []Path) {
+	n := len(paths)
+
+	// exit point of path i (-1 is the start)
+	exit := func(i int) geom.Point {
+		if i < 0 {
+			return start
+		}
+
+		return paths[i].End()
+	}
+
+	// entry point of path i (n is the return to start)
+	entry := func(i int) geom.Point {
+		if i >= n {
+			return start
+		}
+
+		return paths[i].Start
+	}
+
+	for pass := 0; pass < maxOptimizePasses; pass++ {
+		improved := false
+
+		for i := 0; i < n-1; i++ {
+			for j := i + 1; j < n; j++ {
+				before := exit(i-1).Dist(entry(i)) + exit(j).Dist(entry(j+1))
+				after := exit(i-1).Dist(exit(j)) + entry(i).Dist(entry(j+1))
+
+				if after < before-1e-9 {
+					reversePaths(paths[i : j+1])
+					improved = true
+				}
+			}
+		}
+
+		if !improved {
+			return
+		}
+	}
+}
+
+// reversePaths reverses order and direction of paths in place.
+func reversePaths(paths []Path) {
+	for i, j := 0, len(paths)-1; i < j; i, j = i+1, j-1 {
+		paths[i], paths[j] = paths[j], paths[i]
+	}
+
+	for i, p := range paths {
+		if p.Start != p.End() {
+			paths[i] = p.Reverse()
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package toolpath
+
+// Simplify returns polyline without repeated points and with points closer than tolerance
+// to the simplified line removed (Ramer–Douglas–Peucker algorithm).
+// With tolerance 0 only repeated and exactly collinear points are removed.
+// First and last points are always kept (a polyline of identical points becomes a single point).
+func (p Polyline) Simplify(tolerance float64) Polyline {
+	// 1.0: remove repeated points (zero-length lines)
+	points := make(Polyline, 0, len(p))
+	for i, pt := range p {
+		if i == 0 || pt != points[len(points)-1] {
+			points = append(points, pt)
+		}
+	}
+
+	if len(points) <= 2 {
+		return points
+	}
+
+	// 2.0: Ramer–Douglas–Peucker
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+
+	stack := [][2]int{{0, len(points) - 1}}
+	for len(stack) > 0 {
+		first, last := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		farthest, maxDist := -1, tolerance
+		for i := first + 1; i < last; i++ {
+			if d := points[i].DistToSegment(points[first], points[last]); d > maxDist {
+				farthest, maxDist = i, d
+			}
+		}
+
+		if farthest < 0 {
+			continue
+		}
+
+		keep[farthest] = true
+		stack = append(stack, [2]int{first, farthest}, [2]int{farthest, last})
+	}
+
+	result := make(Polyline, 0, len(points))
+	for i, pt := range points {
+		if keep[i] {
+			result = append(result, pt)
+		}
+	}
+
+	return result
+}
+
+// Simplify simplifies straight parts of the path (see Polyline.Simplify).
+// Arcs are kept untouched.
+func (p Path) Simplify(tolerance float64) Path {
+	result := Path{Start: p.Start, Segments: make([]Segment, 0, len(p.Segments))}
+
+	// lines is a run of subsequent lines (starting at the end of the previous segment)
+	lines := Polyline{p.Start}
+
+	flushLines := func() {
+		for _, pt := range lines.Simplify(tolerance)[1:] {
+			result.Segments = append(result.Segments, Segment{End: pt})
+		}
+	}
+
+	for _, seg := range p.Segments {
+		if seg.Arc == nil {
+			lines = append(lines, seg.End)
+			continue
+		}
+
+		flushLines()
+		result.Segments = append(result.Segments, seg)
+		lines = Polyline{seg.End}
+	}
+
+	flushLines()
+
+	return result
+}
@@ -0,0 +1,70 @@
+package toolpath
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+func TestPolyline_Simplify(t *testing.T) {
+	tests := []struct {
+		name      string
+		polyline  Polyline
+		tolerance float64
+		want      Polyline
+	}{
+		{"empty", Polyline{}, 0, Polyline{}},
+		{"single point", Polyline{geom.Pt(1, 1)}, 0, Polyline{geom.Pt(1, 1)}},
+		{"identical points", Polyline{geom.Pt(1, 1), geom.Pt(1, 1), geom.Pt(1, 1)}, 0, Polyline{geom.Pt(1, 1)}},
+		{
+			"repeated points",
+			Polyline{geom.Pt(0, 0), geom.Pt(0, 0), geom.Pt(5, 5), geom.Pt(10, 0), geom.Pt(10, 0)},
+			0,
+			Polyline{geom.Pt(0, 0), geom.Pt(5, 5), geom.Pt(10, 0)},
+		},
+		{
+			"collinear points",
+			Polyline{geom.Pt(0, 0), geom.Pt(1, 0), geom.Pt(2, 0), geom.Pt(10, 0)},
+			0,
+			Polyline{geom.Pt(0, 0), geom.Pt(10, 0)},
+		},
+		{
+			"points within tolerance",
+			Polyline{geom.Pt(0, 0), geom.Pt(5, 0.1), geom.Pt(10, 0), geom.Pt(10, 10)},
+			0.5,
+			Polyline{geom.Pt(0, 0), geom.Pt(10, 0), geom.Pt(10, 10)},
+		},
+		{"closed polyline", square(0, 0, 10, 10), 0, square(0, 0, 10, 10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.polyline.Simplify(tt.tolerance); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPath_Simplify(t *testing.T) {
+	arc := Segment{End: geom.Pt(10, 10), Arc: &Arc{Center: geom.Pt(5, 5), Clockwise: true}}
+
+	tests := []struct {
+		name     string
+		path     Path
+		segments int
+	}{
+		{"identical points", PathFromPolyline(Polyline{geom.Pt(1, 1), geom.Pt(1, 1)}), 0},
+		{"collinear lines", PathFromPolyline(Polyline{geom.Pt(0, 0), geom.Pt(1, 0), geom.Pt(2, 0)}), 1},
+		{"arc is kept", Path{Start: geom.Pt(0, 0), Segments: []Segment{{End: geom.Pt(0, 0)}, arc, {End: geom.Pt(10, 10)}}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.path.Simplify(0); len(got.Segments) != tt.segments {
+				t.Errorf("got %d segments (%v), want %d", len(got.Segments), got.Segments, tt.segments)
+			}
+		})
+	}
+}
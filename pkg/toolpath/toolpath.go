@@ -0,0 +1,267 @@
+// Package toolpath contains an intermediate representation of toolpaths
+// (between SVG and GCode). It allows to inspect and modify geometry
+// before it is converted to GCode (see Emit).
+package toolpath
+
+import (
+	"math"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+// Polyline is a list of points connected with straight lines.
+type Polyline []geom.Point
+
+// Length returns total length of the polyline.
+func (p Polyline) Length() (result float64) {
+	for i := 1; i < len(p); i++ {
+		result += p[i-1].Dist(p[i])
+	}
+
+	return result
+}
+
+// BBox returns bounding box of the polyline.
+func (p Polyline) BBox() geom.Rect {
+	result := geom.EmptyRect()
+	for _, pt := range p {
+		result = result.Extend(pt)
+	}
+
+	return result
+}
+
+// Transform returns polyline transformed by m.
+func (p Polyline) Transform(m geom.Matrix) Polyline {
+	result := make(Polyline, len(p))
+	for i, pt := range p {
+		result[i] = m.Apply(pt)
+	}
+
+	return result
+}
+
+// Reverse returns polyline with reversed order of points.
+func (p Polyline) Reverse() Polyline {
+	result := make(Polyline, len(p))
+	for i, pt := range p {
+		result[len(p)-1-i] = pt
+	}
+
+	return result
+}
+
+// Arc describes a circular arc from the previous point of a path to Segment.End.
+type Arc struct {
+	Center    geom.Point
+	Clockwise bool
+}
+
+// Segment is a single move of a path (from the previous point to End).
+type Segment struct {
+	End geom.Point
+	// Arc is set if the segment is a circular arc (otherwise it is a straight line).
+	Arc *Arc
+}
+
+// Path is a continuous toolpath: tool goes down at Start, follows Segments and goes up at the end.
+type Path struct {
+	Start    geom.Point
+	Segments []Segment
+}
+
+// NewPath creates a new path starting at start.
+func NewPath(start geom.Point) *Path {
+	return &Path{Start: start}
+}
+
+// PathFromPolyline creates a path from polyline.
+func PathFromPolyline(p Polyline) Path {
+	result := Path{Start: p[0], Segments: make([]Segment, 0, len(p)-1)}
+	for _, pt := range p[1:] {
+		result.Segments = append(result.Segments, Segment{End: pt})
+	}
+
+	return result
+}
+
+// LineTo adds a line from the current end of the path to p.
+func (p *Path) LineTo(pt geom.Point) *Path {
+	p.Segments = append(p.Segments, Segment{End: pt})
+	return p
+}
+
+// ArcTo adds a circular arc from the current end of the path to pt around center.
+func (p *Path) ArcTo(pt, center geom.Point, clockwise bool) *Path {
+	p.Segments = append(p.Segments, Segment{End: pt, Arc: &Arc{Center: center, Clockwise: clockwise}})
+	return p
+}
+
+// Close adds a line to the start point if the path is not closed yet.
+func (p *Path) Close() *Path {
+	if p.End() != p.Start {
+		p.LineTo(p.Start)
+	}
+
+	return p
+}
+
+// End returns end point of the path.
+func (p Path) End() geom.Point {
+	if len(p.Segments) == 0 {
+		return p.Start
+	}
+
+	return p.Segments[len(p.Segments)-1].End
+}
+
+// IsEmpty returns true if the path has nothing to draw.
+func (p Path) IsEmpty() bool {
+	return len(p.Segments) == 0
+}
+
+// IsClosed returns true if the path ends within tolerance from its start.
+func (p Path) IsClosed(tolerance float64) bool {
+	return !p.IsEmpty() && p.End().Dist(p.Start) <= tolerance
+}
+
+// arcOf returns geom.Arc of the i-th segment (must be an arc).
+func (p Path) arcOf(i int) geom.Arc {
+	start := p.Start
+	if i > 0 {
+		start = p.Segments[i-1].End
+	}
+
+	seg := p.Segments[i]
+
+	return geom.CircularArc(seg.Arc.Center, start, seg.End, seg.Arc.Clockwise)
+}
+
+// Length returns total length of the path.
+func (p Path) Length() (result float64) {
+	prev := p.Start
+	for i, seg := range p.Segments {
+		if seg.Arc != nil {
+			a := p.arcOf(i)
+			result += math.Abs(a.Delta) * a.RX
+		} else {
+			result += prev.Dist(seg.End)
+		}
+
+		prev = seg.End
+	}
+
+	return result
+}
+
+// Polyline returns the path as a polyline. Arcs are approximated within tolerance.
+func (p Path) Polyline(tolerance float64) Polyline {
+	result := Polyline{p.Start}
+	for i, seg := range p.Segments {
+		if seg.Arc != nil {
+			points := p.arcOf(i).Flatten(tolerance)
+			result = append(result, points[1:len(points)-1]...)
+		}
+
+		result = append(result, seg.End)
+	}
+
+	return result
+}
+
+// BBox returns bounding box of the path (including arcs).
+func (p Path) BBox() geom.Rect {
+	result := geom.EmptyRect().Extend(p.Start)
+	for i, seg := range p.Segments {
+		result = result.Extend(seg.End)
+		if seg.Arc == nil {
+			continue
+		}
+
+		// arc may reach its extreme points (at 0, 90, 180 and 270 degrees)
+		a := p.arcOf(i)
+		for k := -8; k <= 8; k++ {
+			angle := float64(k) * math.Pi / 2
+			if (a.Delta > 0 && angle > a.Start && angle < a.Start+a.Delta) ||
+				(a.Delta < 0 && angle < a.Start && angle > a.Start+a.Delta) {
+				result = result.Extend(a.Point(angle))
+			}
+		}
+	}
+
+	return result
+}
+
+// Transform returns path transformed by m.
+// NOTE: arcs stay circular, so m should only translate, rotate, mirror and scale uniformly.
+func (p Path) Transform(m geom.Matrix) Path {
+	result := Path{Start: m.Apply(p.Start), Segments: make([]Segment, len(p.Segments))}
+	for i, seg := range p.Segments {
+		result.Segments[i] = Segment{End: m.Apply(seg.End)}
+		if seg.Arc != nil {
+			result.Segments[i].Arc = &Arc{
+				Center:    m.Apply(seg.Arc.Center),
+				Clockwise: seg.Arc.Clockwise != (m.Det() < 0),
+			}
+		}
+	}
+
+	return result
+}
+
+// Reverse returns the same path drawn in the opposite direction.
+func (p Path) Reverse() Path {
+	result := Path{Start: p.End(), Segments: make([]Segment, len(p.Segments))}
+	for i, seg := range p.Segments {
+		// segment i goes from point i-1 to i; reversed it goes from i to i-1.
+		start := p.Start
+		if i > 0 {
+			start = p.Segments[i-1].End
+		}
+
+		reversed := Segment{End: start}
+		if seg.Arc != nil {
+			reversed.Arc = &Arc{Center: seg.Arc.Center, Clockwise: !seg.Arc.Clockwise}
+		}
+
+		result.Segments[len(p.Segments)-1-i] = reversed
+	}
+
+	return result
+}
+
+// Layer is a set of paths drawn at the same depth.
+type Layer struct {
+	// Depth is how much deeper (in mm) than the first layer the layer is drawn.
+	Depth float64
+	Paths []Path
+}
+
+// Length returns total drawing length of the layer.
+func (l Layer) Length() (result float64) {
+	for _, p := range l.Paths {
+		result += p.Length()
+	}
+
+	return result
+}
+
+// BBox returns bounding box of all paths in the layer.
+func (l Layer) BBox() geom.Rect {
+	result := geom.EmptyRect()
+	for _, p := range l.Paths {
+		result = result.Union(p.BBox())
+	}
+
+	return result
+}
+
+// Transform returns layer with all paths transformed by m.
+func (l Layer) Transform(m geom.Matrix) Layer {
+	result := Layer{Depth: l.Depth, Paths: make([]Path, len(l.Paths))}
+	for i, p := range l.Paths {
+		result.Paths[i] = p.Transform(m)
+	}
+
+	return result
+}
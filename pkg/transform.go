@@ -0,0 +1,150 @@
+package spiffy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+// parseTransform parses SVG transform attribute (e.g. "translate(10, 20) rotate(45)").
+// refer: https://www.w3.org/TR/css-transforms-1/#svg-transform
+func parseTransform(s string) (geom.Matrix, error) {
+	result := geom.Identity()
+	l := newPathLexer(s)
+
+	for !l.eof() {
+		// 1.0: read function name
+		start := l.pos
+		for l.pos < len(l.data) && l.data[l.pos] != '(' && !isPathSpace(l.data[l.pos]) {
+			l.pos++
+		}
+
+		name := l.data[start:l.pos]
+
+		l.skipSeparators()
+		if l.pos >= len(l.data) || l.data[l.pos] != '(' {
+			return result, l.errorf(l.pos, "expected '(' after %q", name)
+		}
+
+		l.pos++
+
+		// 1.1: read arguments
+		var args []float64
+		for l.hasNumber() {
+			n, err := l.number()
+			if err != nil {
+				return result, err
+			}
+
+			args = append(args, n)
+		}
+
+		l.skipSeparators()
+		if l.pos >= len(l.data) || l.data[l.pos] != ')' {
+			return result, l.errorf(l.pos, "expected ')' after arguments of %q", name)
+		}
+
+		l.pos++
+
+		// 1.2: compute matrix
+		m, err := transformFunction(name, args)
+		if err != nil {
+			return result, fmt.Errorf("%w at position %d: %w", err, start, ErrInvalidTransform)
+		}
+
+		result = result.Mul(m)
+	}
+
+	return result, nil
+}
+
+func transformFunction(name string, args []float64) (geom.Matrix, error) {
+	argsErr := func(expected string) error {
+		return fmt.Errorf("%s expects %s arguments, got %d", name, expected, len(args))
+	}
+
+	switch strings.TrimSpace(name) {
+	case "matrix":
+		if len(args) != 6 {
+			return geom.Matrix{}, argsErr("6")
+		}
+
+		return geom.Matrix{A: args[0], B: args[1], C: args[2], D: args[3], E: args[4], F: args[5]}, nil
+	case "translate":
+		switch len(args) {
+		case 1:
+			return geom.Translate(args[0], 0), nil
+		case 2:
+			return geom.Translate(args[0], args[1]), nil
+		}
+
+		return geom.Matrix{}, argsErr("1 or 2")
+	case "scale":
+		switch len(args) {
+		case 1:
+			return geom.Scale(args[0], args[0]), nil
+		case 2:
+			return geom.Scale(args[0], args[1]), nil
+		}
+
+		return geom.Matrix{}, argsErr("1 or 2")
+	case "rotate":
+		switch len(args) {
+		case 1:
+			return geom.Rotate(args[0]), nil
+		case 3:
+			// rotate around (cx, cy)
+			return geom.Translate(args[1], args[2]).
+				Mul(geom.Rotate(args[0])).
+				Mul(geom.Translate(-args[1], -args[2])), nil
+		}
+
+		return geom.Matrix{}, argsErr("1 or 3")
+	case "skewX":
+		if len(args) != 1 {
+			return geom.Matrix{}, argsErr("1")
+		}
+
+		return geom.SkewX(args[0]), nil
+	case "skewY":
+		if len(args) != 1 {
+			return geom.Matrix{}, argsErr("1")
+		}
+
+		return geom.SkewY(args[0]), nil
+	}
+
+	return geom.Matrix{}, fmt.Errorf("unknown transform function %q", name)
+}
+
+// elementTransform returns transformation defined on the element itself.
+func elementTransform(e *element) (geom.Matrix, error) {
+	t := e.Attr("transform")
+	if t == "" {
+		return geom.Identity(), nil
+	}
+
+	m, err := parseTransform(t)
+	if err != nil {
+		return m, fmt.Errorf("transform of %s: %w", e.ID(), err)
+	}
+
+	return m, nil
+}
+
+// resolveTransform returns current transformation matrix of the element
+// (transforms of all ancestors and the element itself multiplied together).
+func resolveTransform(e *element) (geom.Matrix, error) {
+	result := geom.Identity()
+	for ; e != nil; e = e.Parent {
+		m, err := elementTransform(e)
+		if err != nil {
+			return result, err
+		}
+
+		result = m.Mul(result)
+	}
+
+	return result, nil
+}
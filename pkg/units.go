@@ -0,0 +1,75 @@
+package spiffy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultDPI is a default number of px (SVG user units) per inch (as in CSS).
+const DefaultDPI = 96
+
+// mmPerInch is how many millimeters are in one inch.
+const mmPerInch = 25.4
+
+// unitsPerInch is how many of the unit fit in one inch. px depends on DPI.
+var unitsPerInch = map[string]float64{
+	"in": 1,
+	"cm": mmPerInch / 10,
+	"mm": mmPerInch,
+	"Q":  mmPerInch * 4,
+	"pt": 72,
+	"pc": 6,
+}
+
+// splitLength splits SVG length (e.g. "10.5mm") into value and unit.
+func splitLength(s string) (value float64, unit string, err error) {
+	s = strings.TrimSpace(s)
+
+	i := len(s)
+	for i > 0 && (s[i-1] == '%' || (s[i-1] >= 'a' && s[i-1] <= 'z') || (s[i-1] >= 'A' && s[i-1] <= 'Z')) {
+		i--
+	}
+
+	value, err = strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid length %q: %w", s, err)
+	}
+
+	return value, s[i:], nil
+}
+
+// parseLength parses SVG length attribute and returns it in user units (px).
+// dpi is number of px per inch. Empty string is 0.
+func parseLength(s string, dpi float64) (float64, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+
+	value, unit, err := splitLength(s)
+	if err != nil {
+		return 0, err
+	}
+
+	switch unit {
+	case "", "px":
+		return value, nil
+	}
+
+	perInch, ok := unitsPerInch[unit]
+	if !ok {
+		return 0, fmt.Errorf("unsupported unit %q in length %q", unit, s)
+	}
+
+	return value / perInch * dpi, nil
+}
+
+// parsePhysicalLength parses SVG length attribute and returns it in millimeters.
+func parsePhysicalLength(s string, dpi float64) (float64, error) {
+	px, err := parseLength(s, dpi)
+	if err != nil {
+		return 0, err
+	}
+
+	return px / dpi * mmPerInch, nil
+}
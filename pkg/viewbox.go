@@ -0,0 +1,164 @@
+package spiffy
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gucio321/spiffy/pkg/geom"
+)
+
+// viewBox is a value of the viewBox attribute.
+type viewBox struct {
+	MinX, MinY, Width, Height float64
+}
+
+func parseViewBox(s string) (result viewBox, ok bool, err error) {
+	if strings.TrimSpace(s) == "" {
+		return result, false, nil
+	}
+
+	l := newPathLexer(s)
+
+	var v [4]float64
+	for i := range v {
+		if v[i], err = l.number(); err != nil {
+			return result, false, fmt.Errorf("viewBox %q: %w", s, err)
+		}
+	}
+
+	result = viewBox{v[0], v[1], v[2], v[3]}
+	if result.Width <= 0 || result.Height <= 0 {
+		return result, false, fmt.Errorf("viewBox %q must have positive size", s)
+	}
+
+	return result, true, nil
+}
+
+// documentTransform returns a matrix converting root element's user units to millimeters.
+// It takes width, height and viewBox (with preserveAspectRatio) of the root element into account.
+// If targetWidth and/or targetHeight (in mm) are non-zero, document is scaled to this size
+// (missing one is computed from the aspect ratio).
+// refer: https://www.w3.org/TR/SVG2/coords.html#ComputingAViewportsTransform
+func documentTransform(root *element, dpi, targetWidth, targetHeight float64) (geom.Matrix, error) {
+	// 1.0: get physical size of the document (if set)
+	var width, height float64
+
+	for attr, v := range map[string]*float64{"width": &width, "height": &height} {
+		value := root.Attr(attr)
+		if value == "" || strings.HasSuffix(value, "%") {
+			continue
+		}
+
+		var err error
+		if *v, err = parsePhysicalLength(value, dpi); err != nil {
+			return geom.Identity(), fmt.Errorf("%s of the document: %w", attr, err)
+		}
+	}
+
+	// 2.0: get viewBox. If not present, it's equal to width/height in px.
+	vb, ok, err := parseViewBox(root.Attr("viewBox"))
+	if err != nil {
+		return geom.Identity(), err
+	}
+
+	if !ok {
+		if width <= 0 || height <= 0 {
+			if targetWidth > 0 || targetHeight > 0 {
+				return geom.Identity(), ErrUnknownDocumentSize
+			}
+
+			// user unit is just 1px
+			return geom.Scale(mmPerInch/dpi, mmPerInch/dpi), nil
+		}
+
+		vb = viewBox{0, 0, width / mmPerInch * dpi, height / mmPerInch * dpi}
+	}
+
+	// 3.0: fill missing size from viewBox
+	switch {
+	case width <= 0 && height <= 0:
+		width, height = vb.Width/dpi*mmPerInch, vb.Height/dpi*mmPerInch
+	case width <= 0:
+		width = height * vb.Width / vb.Height
+	case height <= 0:
+		height = width * vb.Height / vb.Width
+	}
+
+	// 4.0: apply target size
+	switch {
+	case targetWidth > 0 && targetHeight > 0:
+		width, height = targetWidth, targetHeight
+	case targetWidth > 0:
+		width, height = targetWidth, height*targetWidth/width
+	case targetHeight > 0:
+		width, height = width*targetHeight/height, targetHeight
+	}
+
+	return viewBoxTransform(vb, width, height, root.Attr("preserveAspectRatio"))
+}
+
+// viewBoxTransform maps vb to viewport of size width x height according to preserveAspectRatio.
+func viewBoxTransform(vb viewBox, width, height float64, preserveAspectRatio string) (geom.Matrix, error) {
+	sx, sy := width/vb.Width, height/vb.Height
+
+	fields := strings.Fields(preserveAspectRatio)
+	if len(fields) > 0 && fields[0] == "defer" {
+		fields = fields[1:]
+	}
+
+	align, meetOrSlice := "xMidYMid", "meet"
+	if len(fields) > 0 {
+		align = fields[0]
+	}
+
+	if len(fields) > 1 {
+		meetOrSlice = fields[1]
+	}
+
+	if align == "none" {
+		return geom.Scale(sx, sy).Mul(geom.Translate(-vb.MinX, -vb.MinY)), nil
+	}
+
+	if len(align) != 8 {
+		return geom.Identity(), fmt.Errorf("invalid preserveAspectRatio %q", preserveAspectRatio)
+	}
+
+	// 1.0: uniform scale
+	var s float64
+
+	switch meetOrSlice {
+	case "meet":
+		s = math.Min(sx, sy)
+	case "slice":
+		s = math.Max(sx, sy)
+	default:
+		return geom.Identity(), fmt.Errorf("invalid preserveAspectRatio %q", preserveAspectRatio)
+	}
+
+	// 2.0: alignment
+	alignment := func(a string, size, vbSize float64) (float64, error) {
+		switch a {
+		case "Min":
+			return 0, nil
+		case "Mid":
+			return (size - vbSize*s) / 2, nil
+		case "Max":
+			return size - vbSize*s, nil
+		}
+
+		return 0, fmt.Errorf("invalid preserveAspectRatio %q", preserveAspectRatio)
+	}
+
+	tx, err := alignment(align[1:4], width, vb.Width)
+	if err != nil {
+		return geom.Identity(), err
+	}
+
+	ty, err := alignment(align[5:8], height, vb.Height)
+	if err != nil {
+		return geom.Identity(), err
+	}
+
+	return geom.Translate(tx, ty).Mul(geom.Scale(s, s)).Mul(geom.Translate(-vb.MinX, -vb.MinY)), nil
+}
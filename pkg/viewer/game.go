@@ -33,6 +33,8 @@ func (v *Viewer) startY() float64 {
 
 const (
 	screenW, screenH = 800, 600
+	// renderTolerance is a tolerance (in mm) of drawing arcs and beziers with lines.
+	renderTolerance = 0.1
 )
 
 var (
@@ -92,24 +94,20 @@ func NewViewer(g *gcb.GCodeBuilder) *Viewer {
 	}
 
 	// claculate Y stats
-	current := 0
+	machine := g.Machine()
 	for _, cmd := range g.Commands() {
-		if cmd.Code != "G0" {
+		block := cmd.Block()
+		segment, err := machine.Execute(&block)
+		if err != nil || segment == nil {
 			continue
 		}
 
-		z, ok := cmd.Args["Z"]
-		if !ok {
-			continue
-		}
-
-		current += int(z)
-		if current < result.Y.Min {
-			result.Y.Min = current
+		if z := int(math.Floor(segment.End.Z)); z < result.Y.Min {
+			result.Y.Min = z
 		}
 
-		if current > result.Y.Max {
-			result.Y.Max = current
+		if z := int(math.Ceil(segment.End.Z)); z > result.Y.Max {
+			result.Y.Max = z
 		}
 	}
 
@@ -135,7 +133,6 @@ func (v *Viewer) render() *ebiten.Image {
 	scale := v.baseScale()
 	dest := ebiten.NewImage(v.w, v.h)
 	dest.Fill(colornames.Black)
-	isDrawing := false
 
 	ebitenutil.DrawLine(dest,
 		float64(v.gcode.Workspace().MaxX-v.gcode.Workspace().MinX)*scale, v.startY()*scale,
@@ -158,57 +155,69 @@ func (v *Viewer) render() *ebiten.Image {
 		int(float64(v.gcode.Workspace().MaxX-v.gcode.Workspace().MinX)*scale/2), int((v.startY()-float64(v.gcode.Workspace().MaxY-v.gcode.Workspace().MinY))*scale)-20,
 	)
 
-	var currentX, currentY float64
+	// absX/absY convert machine coordinates to the screen
+	w := float64(v.gcode.Workspace().MaxX - v.gcode.Workspace().MinX)
+	h := float64(v.gcode.Workspace().MaxY - v.gcode.Workspace().MinY)
+	absX := func(x float64) float64 {
+		result := x - float64(v.gcode.Workspace().MinX)
+		if v.axesModifiers[0] == -1 {
+			result = w - result
+		}
 
-	switch v.axesModifiers[0] {
-	case 1:
-		currentX = float64(gcb.BaseX - v.gcode.Workspace().MinX)
-	case -1:
-		currentX = float64(v.gcode.Workspace().MaxX-v.gcode.Workspace().MinX) - float64(gcb.BaseX-v.gcode.Workspace().MinX)
+		return result
 	}
 
-	switch v.axesModifiers[1] {
-	case 1:
-		currentY = float64(v.startY()) - float64(gcb.BaseY-v.gcode.Workspace().MinY)
-	case -1:
-		currentY = float64(v.gcode.Workspace().MaxY-v.gcode.Workspace().MinY) - (float64(v.startY()) - float64(gcb.BaseY-v.gcode.Workspace().MinY))
+	absY := func(y float64) float64 {
+		result := float64(v.startY()) - (y - float64(v.gcode.Workspace().MinY))
+		if v.axesModifiers[1] == -1 {
+			result = h - result
+		}
+
+		return result
 	}
 
-	currentZ := 0
+	machine := v.gcode.Machine()
 	go func() {
-		for i, cmd := range v.gcode.Commands()[v.cmdRange[0]:endFrame] {
-			v.renderingProgress = float32(i) / float32(endFrame-v.cmdRange[0])
-			switch cmd.Code {
-			case "G0":
-				v.code += cmd.String(true, true) + "\n"
-				if _, ok := cmd.Args["Z"]; ok { // we assume this is up/down command for now
-					if v.showStateChange {
-						ebitenutil.DrawCircle(dest, currentX*scale, currentY*scale, 2, stateChangeColor)
-					}
-
-					currentZ += int(cmd.Args["Z"])
-				}
+		// commands before the range are executed to know where the machine is
+		for i, cmd := range v.gcode.Commands()[:endFrame] {
+			block := cmd.Block()
+			segment, err := machine.Execute(&block)
+			if err != nil {
+				glg.Warnf("Command %d: %v", i, err)
+			}
 
-				_, xChange := cmd.Args["X"]
-				_, yChange := cmd.Args["Y"]
-				if xChange || yChange {
-					newX := currentX + float64(cmd.Args["X"])*float64(v.axesModifiers[0])
-					newY := currentY - float64(cmd.Args["Y"])*float64(v.axesModifiers[1]) // this is because of 0,0 difference
+			if i < int(v.cmdRange[0]) {
+				continue
+			}
+
+			v.renderingProgress = float32(i-int(v.cmdRange[0])) / float32(endFrame-v.cmdRange[0])
+			v.code += cmd.String(true, true) + "\n"
+
+			if segment == nil {
+				continue
+			}
 
-					x := 7 * float64(currentZ-v.Y.Min) / float64(v.Y.Delta)
-					x = x - math.Floor(x)
-					c := GreenToRedHSV(x)
+			if segment.Start.Z != segment.End.Z && v.showStateChange {
+				ebitenutil.DrawCircle(dest, absX(segment.Start.X)*scale, absY(segment.Start.Y)*scale, 2, stateChangeColor)
+			}
 
-					if !((isDrawing && !v.showPrinting) || (!isDrawing && !v.showMoves)) {
-						ebitenutil.DrawLine(dest, currentX*scale, currentY*scale, newX*scale, newY*scale, c)
-					}
+			isDrawing := !segment.Rapid
+			if (isDrawing && !v.showPrinting) || (!isDrawing && !v.showMoves) {
+				continue
+			}
 
-					currentX, currentY = newX, newY
+			points := segment.Flatten(renderTolerance)
+			for j := 1; j < len(points); j++ {
+				from, to := points[j-1], points[j]
+				if from.X == to.X && from.Y == to.Y {
+					continue
 				}
-			case "":
-				v.code += cmd.String(true, true) + "\n"
-			default:
-				glg.Warnf("Unknown command: %s", cmd.Code)
+
+				x := 7 * (from.Z - float64(v.Y.Min)) / float64(v.Y.Delta)
+				x = x - math.Floor(x)
+				c := GreenToRedHSV(x)
+
+				ebitenutil.DrawLine(dest, absX(from.X)*scale, absY(from.Y)*scale, absX(to.X)*scale, absY(to.Y)*scale, c)
 			}
 		}
 
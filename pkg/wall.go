@@ -0,0 +1,109 @@
+package spiffy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gucio321/spiffy/pkg/toolpath"
+)
+
+// WallProfile returns a wall angle (in degrees from horizontal, 0 < angle <= 90)
+// of the formed part at the depth (in mm below the first pass). See RepeatOffset.
+type WallProfile func(depth float64) float64
+
+// ConstantWall returns a profile of a constant wall angle (e.g. cone or pyramid).
+func ConstantWall(angle float64) WallProfile {
+	return func(float64) float64 {
+		return angle
+	}
+}
+
+// WallPoint is a wall angle at the depth (see LinearWall).
+type WallPoint struct {
+	Depth, Angle float64
+}
+
+// LinearWall returns a profile interpolating linearly between the points.
+// Above the first and below the last point the angle is constant.
+func LinearWall(points ...WallPoint) WallProfile {
+	points = append([]WallPoint{}, points...)
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Depth < points[j].Depth
+	})
+
+	return func(depth float64) float64 {
+		if len(points) == 0 {
+			return 90
+		}
+
+		i := sort.Search(len(points), func(i int) bool {
+			return points[i].Depth >= depth
+		})
+
+		switch {
+		case i == 0:
+			return points[0].Angle
+		case i == len(points):
+			return points[len(points)-1].Angle
+		}
+
+		a, b := points[i-1], points[i]
+		t := (depth - a.Depth) / (b.Depth - a.Depth)
+
+		return a.Angle + t*(b.Angle-a.Angle)
+	}
+}
+
+// WallAngle sets a constant wall angle (in degrees from horizontal) used by RepeatOffset.
+func (s *Spiffy) WallAngle(angle float64) *Spiffy {
+	return s.WallProfile(ConstantWall(angle))
+}
+
+// WallProfile sets a wall angle varying with depth used by RepeatOffset.
+func (s *Spiffy) WallProfile(profile WallProfile) *Spiffy {
+	s.repeat.wall = profile
+	return s
+}
+
+// offsetOf returns how much (in mm) paths of the n-th repeat are offset inward.
+// Every repeat goes down by moveDown and in by moveDown / tan(wall angle)
+// (angle is taken in the middle of the step).
+func (s *Spiffy) offsetOf(n int) (result float64, err error) {
+	if s.repeat.wall == nil {
+		return 0, nil
+	}
+
+	for i := 1; i <= n; i++ {
+		depth := (float64(i) - 0.5) * s.repeat.moveDown
+		angle := s.repeat.wall(depth)
+		if angle <= 0 || angle > 90 {
+			return 0, fmt.Errorf("%v degrees at depth %.2f mm: %w", angle, depth, ErrInvalidWallAngle)
+		}
+
+		result += s.repeat.moveDown / math.Tan(angle*math.Pi/180)
+	}
+
+	return result, nil
+}
+
+// offsetPaths offsets closed paths by distance (see toolpath.Path.Offset): outer paths inward
+// and holes in them (see toolpath.Nesting) outward, so that all walls slope the same way.
+// Vanished paths are skipped.
+// NOTE: holes are not clipped when they grow over outer paths.
+func (s *Spiffy) offsetPaths(paths []toolpath.Path, distance float64) []toolpath.Path {
+	nesting := toolpath.Nesting(paths, s.tolerance)
+	result := make([]toolpath.Path, 0, len(paths))
+	for i, p := range paths {
+		d := distance
+		if nesting[i]%2 == 1 {
+			d = -distance
+		}
+
+		if offset, ok := p.Offset(d, s.tolerance); ok {
+			result = append(result, offset)
+		}
+	}
+
+	return result
+}
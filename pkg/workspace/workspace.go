@@ -15,10 +15,33 @@ type Workspace struct {
 	// MaxX and MaxY represent the point counting from printers (0,0)
 	MaxX, MaxY int
 
+	// BaseX and BaseY is a position (counting from printers (0,0)) where the job starts and ends.
+	// If both are 0, DefaultBaseX/DefaultBaseY is used (see Base).
+	BaseX, BaseY int
+
+	// NativeArcs is true if the machine supports G2/G3 arc moves.
+	NativeArcs bool
+
+	// Preamble and Postamble are paths to text/template files with code executed
+	// before/after the job (see gcb.TemplateData). Empty means dialect's default.
+	Preamble, Postamble string
+
 	Name        string
 	Description string
 }
 
+// DefaultBaseX, DefaultBaseY is a default base position (see Workspace.BaseX).
+const DefaultBaseX, DefaultBaseY = 80, 80
+
+// Base returns the base position (see BaseX, BaseY).
+func (w *Workspace) Base() (x, y int) {
+	if w.BaseX == 0 && w.BaseY == 0 {
+		return DefaultBaseX, DefaultBaseY
+	}
+
+	return w.BaseX, w.BaseY
+}
+
 func decodeWorkspaces() ([]Workspace, error) {
 	var result []Workspace
 	if err := json.Unmarshal(workspaces, &result); err != nil {